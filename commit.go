@@ -14,7 +14,7 @@ func NewCommitCmd() *cobra.Command {
 		Use:     "commit [flags]",
 		Example: "commit -m \"commit message\"",
 		Args:    cobra.NoArgs,
-		RunE:    cmdCommitRun,
+		RunE:    journaled(cmdCommitRun),
 	}
 
 	cmd.PersistentFlags().StringP("message", "m", "", "message")
@@ -34,7 +34,7 @@ func cmdCommitRun(cmd *cobra.Command, _ []string) error {
 	}
 	slog.Info("args", slog.String("message", message))
 	changeId := bson.NewObjectID().Hex()
-	changeIdMessage := fmt.Sprintf("change-id: %s", changeId)
+	changeIdMessage := fmt.Sprintf("%s: %s", cfg.Trailer.Key, changeId)
 	args := []string{"commit", "-m", message, "-m", changeIdMessage}
 	_, err = exec.OutputErr("git", args...)
 	return err
@@ -0,0 +1,48 @@
+package dx
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/kitimark/dx/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:  "config [command]",
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:  "show",
+		Long: "show prints the effective config values and where each one came from",
+		Args: cobra.NoArgs,
+		RunE: cmdConfigShowRun,
+	})
+
+	return cmd
+}
+
+func cmdConfigShowRun(_ *cobra.Command, _ []string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, v := range cfg.Values() {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Key, v.Value, v.Source)
+	}
+	return w.Flush()
+}
+
+func loadConfig() error {
+	root, err := getRepoRoot()
+	if err != nil {
+		return err
+	}
+	c, err := config.Load(root)
+	if err != nil {
+		return err
+	}
+	cfg = c
+	return nil
+}
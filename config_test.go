@@ -0,0 +1,19 @@
+package dx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigShow(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	twrite(t, clientDir+"/.dx.yaml", "remote: upstream\n")
+	trun(t, clientDir, "git", "config", "dx.remote", "origin")
+
+	err := trunMainCommand(t, "config", "show")
+	require.NoError(t, err)
+	assert.Equal(t, "origin", cfg.Remote)
+	assert.Equal(t, "git config", cfg.Source("remote"))
+}
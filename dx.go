@@ -13,11 +13,18 @@ func NewMainCmd() *cobra.Command {
 	}
 
 	cmd.PersistentFlags().BoolP("debug", "d", false, "print debug info")
+	cmd.PersistentFlags().String("base", "", "override the main branch that feature branches are based on")
 
 	cmd.AddCommand(NewCommitCmd())
 	cmd.AddCommand(NewSyncCmd())
 	cmd.AddCommand(NewVersionCmd())
 	cmd.AddCommand(NewResolveConflictCmd())
+	cmd.AddCommand(NewGcCmd())
+	cmd.AddCommand(NewUndoCmd())
+	cmd.AddCommand(NewOpCmd())
+	cmd.AddCommand(NewConfigCmd())
+	cmd.AddCommand(NewBranchCmd())
+	cmd.AddCommand(NewStackCmd())
 
 	return cmd
 }
@@ -31,7 +38,19 @@ func cmdMainPreRun(cmd *cobra.Command, _ []string) error {
 		slog.SetLogLoggerLevel(slog.LevelDebug)
 	}
 
-	err = gitInit()
+	err = loadConfig()
+	if err != nil {
+		return err
+	}
+	base, err := cmd.Flags().GetString("base")
+	if err != nil {
+		return err
+	}
+	err = gitInit(base)
+	if err != nil {
+		return err
+	}
+	err = detectInterruptedSyncs()
 	if err != nil {
 		return err
 	}
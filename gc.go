@@ -0,0 +1,188 @@
+package dx
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kitimark/dx/pkg/exec"
+	"github.com/spf13/cobra"
+)
+
+func NewGcCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "gc [flags]",
+		Example: "gc --prune --older-than 72h",
+		Args:    cobra.NoArgs,
+		RunE:    journaled(cmdGcRun),
+	}
+
+	cmd.PersistentFlags().Bool("prune", false, "delete abandoned temporary sync branches")
+	cmd.PersistentFlags().Duration("older-than", 24*time.Hour, "only prune temporary sync branches older than this age")
+
+	return cmd
+}
+
+func cmdGcRun(cmd *cobra.Command, _ []string) error {
+	flags := cmd.Flags()
+	prune, err := flags.GetBool("prune")
+	if err != nil {
+		return err
+	}
+	olderThan, err := flags.GetDuration("older-than")
+	if err != nil {
+		return err
+	}
+
+	branches, err := listStaleSyncBranches()
+	if err != nil {
+		return err
+	}
+	if len(branches) == 0 {
+		slog.Info("no temporary sync branches found")
+		return nil
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BRANCH\tFROM\tTO\tAGE\tSTATE")
+	for _, b := range branches {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", b.name, b.from, b.to,
+			b.age(now).Truncate(time.Second), b.state())
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	if !prune {
+		return nil
+	}
+	for _, b := range branches {
+		if !b.isAbandoned() {
+			slog.Info("skip branch that is still in use", "branch", b.name, "state", b.state())
+			continue
+		}
+		if b.age(now) < olderThan {
+			slog.Info("skip branch that is newer than threshold", "branch", b.name, "older_than", olderThan)
+			continue
+		}
+		out, err := exec.OutputErr("git", "branch", "-D", b.name)
+		if err != nil {
+			return fmt.Errorf("cannot delete branch %s: %s: %w", b.name, out, err)
+		}
+		slog.Info("pruned temporary sync branch", "branch", b.name)
+	}
+	return nil
+}
+
+type staleSyncBranch struct {
+	*tmpSyncBranch
+	// worktree is the path of worktree that checkout this branch, empty if none
+	worktree string
+	// cherryPicking is true if cherry-pick is still in progress on this branch
+	cherryPicking bool
+}
+
+func (b *staleSyncBranch) age(now time.Time) time.Duration {
+	return now.Sub(b.id.Timestamp())
+}
+
+func (b *staleSyncBranch) isAbandoned() bool {
+	return b.worktree == ""
+}
+
+func (b *staleSyncBranch) state() string {
+	switch {
+	case b.cherryPicking:
+		return "cherry-pick in progress"
+	case b.worktree != "":
+		return "checked out"
+	default:
+		return "abandoned"
+	}
+}
+
+// listStaleSyncBranches returns every temporary sync branch with its
+// checkout state across all worktrees
+func listStaleSyncBranches() ([]*staleSyncBranch, error) {
+	out, err := exec.OutputErr("git", "branch", "--list", tmpSyncBranchPrefix+"*",
+		"--format", "%(refname:short)")
+	if err != nil {
+		return nil, fmt.Errorf("error during list branch: %s: %w", out, err)
+	}
+	worktrees, err := getWorktreeBranches()
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []*staleSyncBranch
+	for _, name := range strings.Split(out, "\n") {
+		if name == "" {
+			continue
+		}
+		tmpBranch, err := parseTempSyncBranch(name)
+		if err != nil {
+			slog.Warn("skip invalid temporary sync branch", "branch", name, "error", err)
+			continue
+		}
+		b := &staleSyncBranch{
+			tmpSyncBranch: tmpBranch,
+			worktree:      worktrees[name],
+		}
+		if b.worktree != "" {
+			b.cherryPicking, err = isCherryPickInProgress(b.worktree)
+			if err != nil {
+				return nil, err
+			}
+		}
+		branches = append(branches, b)
+	}
+	return branches, nil
+}
+
+// getWorktreeBranches returns map of branch name to worktree path that parsed
+// from `git worktree list --porcelain`
+//
+// ### Example output of `git worktree list --porcelain`
+//
+//	worktree /path/to/repo
+//	HEAD 0becbfe5b066fa153d7b253be6bdd9b211d7918b
+//	branch refs/heads/feature
+func getWorktreeBranches() (map[string]string, error) {
+	out, err := exec.OutputErr("git", "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("error during list worktree: %s: %w", out, err)
+	}
+	branches := map[string]string{}
+	var worktree string
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			worktree = line[len("worktree "):]
+		case strings.HasPrefix(line, "branch refs/heads/"):
+			branches[line[len("branch refs/heads/"):]] = worktree
+		}
+	}
+	return branches, nil
+}
+
+func isCherryPickInProgress(worktree string) (bool, error) {
+	out, err := exec.OutputErr("git", "-C", worktree, "rev-parse", "--git-path", "CHERRY_PICK_HEAD")
+	if err != nil {
+		return false, fmt.Errorf("error during get git path: %s: %w", out, err)
+	}
+	path := strings.TrimRight(out, "\n")
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(worktree, path)
+	}
+	_, err = os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
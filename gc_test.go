@@ -0,0 +1,61 @@
+package dx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGc_PruneAbandonedSyncBranch(t *testing.T) {
+	serverDir, clientDir := newGitTest(t)
+
+	t.Log("server: make commit is git server")
+	trun(t, serverDir, "git", "checkout", "dev")
+	twrite(t, serverDir+"/main", "srv_feature1\n")
+	trun(t, serverDir, "git", "add", "main")
+	trun(t, serverDir, "git", "commit", "-m", "feat: server feature 1")
+
+	t.Log("client: develop feature branch")
+	trun(t, clientDir, "git", "checkout", "-b", "client_feature1")
+	twrite(t, clientDir+"/main", "client_feature1\n")
+	trun(t, clientDir, "git", "add", "main")
+	err := trunMainCommand(t, "commit", "-m", "feat: client feature 1")
+	require.NoError(t, err)
+
+	t.Log("client: sync got conflict")
+	err = trunMainCommand(t, "sync", "dev")
+	require.ErrorContains(t, err, "code conflict")
+	assertBranchExist(t, clientDir, "tmp-sync*")
+
+	branches, err := listStaleSyncBranches()
+	require.NoError(t, err)
+	require.Len(t, branches, 1)
+	assert.Equal(t, "client_feature1", branches[0].from)
+	assert.Equal(t, "dev", branches[0].to)
+	assert.True(t, branches[0].cherryPicking)
+	assert.Equal(t, "cherry-pick in progress", branches[0].state())
+
+	t.Log("client: prune must keep branch with cherry-pick in progress")
+	err = trunMainCommand(t, "gc", "--prune", "--older-than", "0s")
+	require.NoError(t, err)
+	assertBranchExist(t, clientDir, "tmp-sync*")
+
+	t.Log("client: abandon the sync")
+	trun(t, clientDir, "git", "cherry-pick", "--abort")
+	trun(t, clientDir, "git", "checkout", "client_feature1")
+
+	branches, err = listStaleSyncBranches()
+	require.NoError(t, err)
+	require.Len(t, branches, 1)
+	assert.Equal(t, "abandoned", branches[0].state())
+
+	t.Log("client: prune must keep branch newer than threshold")
+	err = trunMainCommand(t, "gc", "--prune")
+	require.NoError(t, err)
+	assertBranchExist(t, clientDir, "tmp-sync*")
+
+	err = trunMainCommand(t, "gc", "--prune", "--older-than", "0s")
+	require.NoError(t, err)
+	assertNoBranch(t, clientDir, "tmp-sync*")
+}
@@ -2,16 +2,51 @@ package dx
 
 import (
 	"fmt"
+	"path/filepath"
 	"slices"
 	"strings"
 
+	"github.com/kitimark/dx/pkg/config"
 	"github.com/kitimark/dx/pkg/exec"
 )
 
 var mainBranchName string
 var defaultMainBranchName = []string{"master", "main"}
 
-func gitInit() error {
+// cfg is the effective config of current repository, it's loaded before any command run
+var cfg = config.Default()
+
+// gitInit resolves the main branch in order of base override, mainBranch config
+// (see config.Load), `refs/remotes/<remote>/HEAD` and then the default main branch names
+func gitInit(base string) error {
+	mainBranchName = ""
+	if base != "" {
+		b, ok := resolveBranch(base)
+		if !ok {
+			return fmt.Errorf("base branch %s is not found", base)
+		}
+		mainBranchName = b
+		return nil
+	}
+
+	if cfg.MainBranch != "" {
+		b, ok := resolveBranch(cfg.MainBranch)
+		if !ok {
+			return fmt.Errorf("main branch %s from %s is not found", cfg.MainBranch, cfg.Source("mainBranch"))
+		}
+		mainBranchName = b
+		return nil
+	}
+
+	out, err := exec.OutputErr("git", "symbolic-ref", "--quiet", "--short", "refs/remotes/"+cfg.Remote+"/HEAD")
+	if err == nil {
+		remoteHead := strings.TrimPrefix(strings.TrimRight(out, "\n"), cfg.Remote+"/")
+		if b, ok := resolveBranch(remoteHead); ok {
+			mainBranchName = b
+			return nil
+		}
+	}
+
 	args := append([]string{"branch", "--list"}, defaultMainBranchName...)
 	args = append(args, "--format", "%(refname:short)")
 	branches, err := exec.OutputErr("git", args...)
@@ -25,12 +60,32 @@ func gitInit() error {
 			break
 		}
 	}
+	if mainBranchName == "" {
+		for _, name := range defaultMainBranchName {
+			if b, ok := resolveBranch(name); ok {
+				mainBranchName = b
+				break
+			}
+		}
+	}
 	if mainBranchName == "" {
 		return fmt.Errorf("main (%s) branch is not found", strings.Join(defaultMainBranchName, ","))
 	}
 	return nil
 }
 
+// resolveBranch returns the local branch if it exists,
+// otherwise its remote-tracking branch
+func resolveBranch(name string) (string, bool) {
+	for _, b := range []string{name, cfg.Remote + "/" + name} {
+		_, err := exec.OutputErr("git", "rev-parse", "--verify", "--quiet", b+"^{commit}")
+		if err == nil {
+			return b, true
+		}
+	}
+	return "", false
+}
+
 type Commit struct {
 	Hash      string
 	Message   string
@@ -87,8 +142,36 @@ func parseSubCommit(msg string) []*Commit {
 }
 
 func parseCommitId(c *Commit, line string) {
-	if strings.HasPrefix(line, "change-id: ") {
-		changeId := line[len("change-id: "):]
+	prefix := cfg.Trailer.Key + ": "
+	if strings.HasPrefix(line, prefix) {
+		changeId := line[len(prefix):]
 		c.ChangeIDs = []string{changeId}
 	}
 }
+
+func getRepoRoot() (string, error) {
+	out, err := exec.OutputErr("git", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("error during get repository root: %s: %w", out, err)
+	}
+	return strings.TrimRight(out, "\n"), nil
+}
+
+// getDxDir returns the directory that dx keeps its state in, it's shared
+// between all worktrees of the repository
+func getDxDir() (string, error) {
+	out, err := exec.OutputErr("git", "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("error during get git dir: %s: %w", out, err)
+	}
+	gitDir, err := filepath.Abs(strings.TrimRight(out, "\n"))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gitDir, "dx"), nil
+}
+
+func isBranchExist(branch string) bool {
+	_, err := exec.OutputErr("git", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
@@ -4,17 +4,70 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDefaultBranchName(t *testing.T) {
 	_, clientDir := newGitTest(t)
 	trun(t, clientDir, "git", "checkout", "-b", "feature")
 
-	err := gitInit()
+	err := gitInit("")
 	assert.NoError(t, err)
 	assert.Equal(t, "main", mainBranchName)
 }
 
+func TestMainBranchName_GitConfig(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	trun(t, clientDir, "git", "checkout", "-b", "develop")
+	trun(t, clientDir, "git", "config", "dx.mainBranch", "develop")
+
+	require.NoError(t, loadConfig())
+	err := gitInit("")
+	assert.NoError(t, err)
+	assert.Equal(t, "develop", mainBranchName)
+
+	trun(t, clientDir, "git", "config", "dx.mainBranch", "unknown")
+	require.NoError(t, loadConfig())
+	err = gitInit("")
+	assert.ErrorContains(t, err, "main branch unknown from git config is not found")
+}
+
+func TestMainBranchName_RemoteHead(t *testing.T) {
+	serverDir, clientDir := newGitTest(t)
+	trun(t, serverDir, "git", "branch", "-m", "main", "trunk")
+	trun(t, clientDir, "git", "fetch", "--prune")
+	trun(t, clientDir, "git", "remote", "set-head", "origin", "trunk")
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	trun(t, clientDir, "git", "branch", "-D", "main")
+
+	err := gitInit("")
+	assert.NoError(t, err)
+	assert.Equal(t, "origin/trunk", mainBranchName)
+}
+
+func TestMainBranchName_RemoteOnly(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	trun(t, clientDir, "git", "branch", "-D", "main")
+	trun(t, clientDir, "git", "remote", "set-head", "origin", "--delete")
+
+	err := gitInit("")
+	assert.NoError(t, err)
+	assert.Equal(t, "origin/main", mainBranchName)
+}
+
+func TestMainBranchName_BaseOverride(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+
+	err := gitInit("dev")
+	assert.NoError(t, err)
+	assert.Equal(t, "dev", mainBranchName)
+
+	err = trunMainCommand(t, "--base", "unknown", "version")
+	assert.ErrorContains(t, err, "base branch unknown is not found")
+}
+
 func TestParseCommits(t *testing.T) {
 	testcases := []struct {
 		name     string
@@ -0,0 +1,234 @@
+package dx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// syncLock is an advisory lock that prevents concurrent syncs into the same
+// target branch. it's kept under `.git/dx/locks/<escaped target>` for the whole
+// sync, including while the sync is paused on a code conflict.
+type syncLock struct {
+	path string
+
+	Target    string    `json:"target"`
+	From      string    `json:"from"`
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	CreatedAt time.Time `json:"created_at"`
+	// TmpBranch is the temporary sync branch of this sync
+	TmpBranch string `json:"tmp_branch"`
+	// Paused is true while the sync is waiting for `dx sync --continue`
+	Paused bool `json:"paused"`
+}
+
+var errSyncLockExist = errors.New("sync lock exist")
+
+func getSyncLockPath(target string) (string, error) {
+	dxDir, err := getDxDir()
+	if err != nil {
+		return "", err
+	}
+	// escape slash, so release and release/1.0 don't collide as file and directory
+	return filepath.Join(dxDir, "locks", url.PathEscape(target)), nil
+}
+
+// acquireSyncLock takes the lock of target branch, stale lock that left by
+// dead process will be replaced.
+func acquireSyncLock(target, from, tmpBranch string) (*syncLock, error) {
+	path, err := getSyncLockPath(target)
+	if err != nil {
+		return nil, err
+	}
+	l := newSyncLock(path, target, from, tmpBranch)
+	err = l.create()
+	if !errors.Is(err, errSyncLockExist) {
+		return l, err
+	}
+
+	holder, err := readSyncLock(path)
+	if err != nil {
+		return nil, err
+	}
+	if !holder.isStale() {
+		return nil, holder.heldError()
+	}
+	slog.Warn("remove stale sync lock", "target", target, "pid", holder.PID, "host", holder.Host)
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	err = l.create()
+	if errors.Is(err, errSyncLockExist) {
+		return nil, fmt.Errorf("sync into %s is locked by another process", target)
+	}
+	return l, err
+}
+
+// resumeSyncLock takes over the lock that paused by tmpBranch
+// when continue the sync
+func resumeSyncLock(target, from, tmpBranch string) (*syncLock, error) {
+	path, err := getSyncLockPath(target)
+	if err != nil {
+		return nil, err
+	}
+	holder, err := readSyncLock(path)
+	if os.IsNotExist(err) {
+		return acquireSyncLock(target, from, tmpBranch)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if holder.TmpBranch != tmpBranch && !holder.isStale() {
+		return nil, holder.heldError()
+	}
+	l := newSyncLock(path, target, from, tmpBranch)
+	return l, l.write()
+}
+
+func newSyncLock(path, target, from, tmpBranch string) *syncLock {
+	host, _ := os.Hostname()
+	return &syncLock{
+		path:      path,
+		Target:    target,
+		From:      from,
+		PID:       os.Getpid(),
+		Host:      host,
+		CreatedAt: time.Now(),
+		TmpBranch: tmpBranch,
+	}
+}
+
+func readSyncLock(path string) (*syncLock, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &syncLock{path: path}
+	err = json.Unmarshal(b, l)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sync lock %s: %w", path, err)
+	}
+	return l, nil
+}
+
+func (l *syncLock) create() error {
+	err := os.MkdirAll(filepath.Dir(l.path), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if os.IsExist(err) {
+		return errSyncLockExist
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(l)
+}
+
+func (l *syncLock) write() error {
+	err := os.MkdirAll(filepath.Dir(l.path), 0755)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	tmpPath := l.path + ".tmp"
+	err = os.WriteFile(tmpPath, append(b, '\n'), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, l.path)
+}
+
+// pause keeps the lock after the process exit until the sync is continued
+func (l *syncLock) pause() {
+	l.Paused = true
+	err := l.write()
+	if err != nil {
+		slog.Warn("cannot pause sync lock", "path", l.path, "error", err)
+	}
+}
+
+func (l *syncLock) release() {
+	if l.Paused {
+		return
+	}
+	err := os.Remove(l.path)
+	if err != nil && !os.IsNotExist(err) {
+		slog.Warn("cannot release sync lock", "path", l.path, "error", err)
+	}
+}
+
+// isStale returns true if the lock holder is gone. paused lock is stale
+// when its temporary sync branch is removed, otherwise the lock is stale
+// when its process is not alive on the same host.
+func (l *syncLock) isStale() bool {
+	if l.Paused {
+		return !isBranchExist(l.TmpBranch)
+	}
+	host, _ := os.Hostname()
+	if l.Host != host {
+		return false
+	}
+	return !isProcessAlive(l.PID)
+}
+
+func (l *syncLock) heldError() error {
+	if l.Paused {
+		return fmt.Errorf("sync into %s from %s is paused on code conflict since %s\n"+
+			"hint: checkout %s and run \"dx sync --continue\", or run \"dx gc --prune\" after abort it",
+			l.Target, l.From, l.CreatedAt.Format(time.RFC3339), l.TmpBranch)
+	}
+	return fmt.Errorf("sync into %s is locked by pid %d on %s (sync from %s since %s)\n"+
+		"hint: remove %s if the process is gone",
+		l.Target, l.PID, l.Host, l.From, l.CreatedAt.Format(time.RFC3339), l.path)
+}
+
+// detectInterruptedSyncs reports the syncs that ended without cleanup, e.g. the
+// process is killed. the stale lock is removed after it's reported.
+func detectInterruptedSyncs() error {
+	dxDir, err := getDxDir()
+	if err != nil {
+		return err
+	}
+	locksDir := filepath.Join(dxDir, "locks")
+	return filepath.WalkDir(locksDir, func(path string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil || d.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return err
+		}
+		l, err := readSyncLock(path)
+		if err != nil {
+			slog.Warn("cannot read sync lock", "error", err)
+			return nil
+		}
+		if l.Paused || !l.isStale() {
+			return nil
+		}
+		slog.Warn("previous sync was interrupted, the repository might have partial state",
+			"target", l.Target, "from", l.From, "pid", l.PID, "tmp_branch", l.TmpBranch)
+		if isBranchExist(l.TmpBranch) {
+			slog.Warn(`hint: run "dx gc" to inspect the temporary sync branch`)
+		}
+		err = os.Remove(path)
+		if err != nil {
+			slog.Warn("cannot remove stale sync lock", "path", path, "error", err)
+		}
+		return nil
+	})
+}
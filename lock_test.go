@@ -0,0 +1,119 @@
+package dx
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncLock_LockedByAnotherProcess(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	twrite(t, clientDir+"/content", "hello world")
+	trun(t, clientDir, "git", "add", "content")
+	err := trunMainCommand(t, "commit", "-m", "commit message")
+	require.NoError(t, err)
+
+	t.Log("another sync is running in this clone")
+	path, err := getSyncLockPath("dev")
+	require.NoError(t, err)
+	holder := newSyncLock(path, "dev", "another_feature", "tmp-sync-another")
+	require.NoError(t, holder.create())
+
+	err = trunMainCommand(t, "sync", "dev")
+	require.ErrorContains(t, err, "sync into dev is locked by pid")
+	assert.ErrorContains(t, err, "another_feature")
+	assert.Equal(t, "feature", tgetHeadBranch(t, clientDir))
+
+	t.Log("the holder is gone")
+	deadCmd := exec.Command("true")
+	require.NoError(t, deadCmd.Run())
+	holder.PID = deadCmd.Process.Pid
+	require.NoError(t, holder.write())
+
+	err = trunMainCommand(t, "sync", "dev")
+	require.NoError(t, err)
+	assert.NoFileExists(t, path)
+	assertNormalTeardown(t, clientDir)
+}
+
+func TestSyncLock_KeepLockDuringConflict(t *testing.T) {
+	serverDir, clientDir := newGitTest(t)
+
+	trun(t, serverDir, "git", "checkout", "dev")
+	twrite(t, serverDir+"/main", "srv_feature1\n")
+	trun(t, serverDir, "git", "add", "main")
+	trun(t, serverDir, "git", "commit", "-m", "feat: server feature 1")
+
+	trun(t, clientDir, "git", "checkout", "-b", "client_feature1")
+	twrite(t, clientDir+"/main", "client_feature1\n")
+	trun(t, clientDir, "git", "add", "main")
+	err := trunMainCommand(t, "commit", "-m", "feat: client feature 1")
+	require.NoError(t, err)
+
+	err = trunMainCommand(t, "sync", "dev")
+	require.ErrorContains(t, err, "code conflict")
+	path, err := getSyncLockPath("dev")
+	require.NoError(t, err)
+	lock, err := readSyncLock(path)
+	require.NoError(t, err)
+	assert.True(t, lock.Paused)
+	assert.False(t, lock.isStale())
+
+	t.Log("another sync must wait for the paused sync")
+	_, err = acquireSyncLock("dev", "another_feature", "tmp-sync-another")
+	require.ErrorContains(t, err, "paused on code conflict")
+
+	out := removeConflictAnnotate(t, tread(t, clientDir+"/main"))
+	twrite(t, clientDir+"/main", out)
+	trun(t, clientDir, "git", "add", "main")
+	err = trunMainCommand(t, "sync", "--continue")
+	require.NoError(t, err)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "lock must be released")
+	assertNormalTeardown(t, clientDir)
+}
+
+func TestSyncLock_StalePausedLock(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+
+	path, err := getSyncLockPath("dev")
+	require.NoError(t, err)
+	holder := newSyncLock(path, "dev", "another_feature", "tmp-sync-removed")
+	holder.CreatedAt = time.Now().Add(-time.Hour)
+	holder.pause()
+
+	lock, err := acquireSyncLock("dev", "feature", "tmp-sync-feature")
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), lock.PID)
+	lock.release()
+	assert.NoFileExists(t, path)
+}
+
+func TestSyncLock_NestedTargetNames(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+
+	release, err := acquireSyncLock("release", "feature", "tmp-sync-release")
+	require.NoError(t, err)
+	release10, err := acquireSyncLock("release/1.0", "feature", "tmp-sync-release-1.0")
+	require.NoError(t, err)
+	assert.NotEqual(t, release.path, release10.path)
+
+	t.Log("the stale lock of nested target is found")
+	deadCmd := exec.Command("true")
+	require.NoError(t, deadCmd.Run())
+	release10.PID = deadCmd.Process.Pid
+	require.NoError(t, release10.write())
+	require.NoError(t, detectInterruptedSyncs())
+	assert.NoFileExists(t, release10.path)
+
+	release.release()
+	assert.NoFileExists(t, release.path)
+}
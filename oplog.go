@@ -0,0 +1,268 @@
+package dx
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kitimark/dx/pkg/exec"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func NewOpCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:  "op [command]",
+		Args: cobra.NoArgs,
+	}
+
+	logCmd := &cobra.Command{
+		Use:  "log [flags]",
+		Args: cobra.NoArgs,
+		RunE: cmdOpLogRun,
+	}
+	logCmd.PersistentFlags().IntP("limit", "n", 0, "limit the number of operations to show")
+	cmd.AddCommand(logCmd)
+
+	return cmd
+}
+
+func cmdOpLogRun(cmd *cobra.Command, _ []string) error {
+	limit, err := cmd.Flags().GetInt("limit")
+	if err != nil {
+		return err
+	}
+	ops, err := readOperations()
+	if err != nil {
+		return err
+	}
+	slices.Reverse(ops)
+	if limit > 0 && len(ops) > limit {
+		ops = ops[:limit]
+	}
+	for _, op := range ops {
+		fmt.Printf("%s %s %s\n", op.ID, op.Time.Local().Format(time.DateTime), op.command())
+		if op.HeadBefore != op.HeadAfter {
+			fmt.Printf("    HEAD: %s -> %s\n", shortRef(op.HeadBefore), shortRef(op.HeadAfter))
+		}
+		for _, r := range op.Refs {
+			fmt.Printf("    %s: %s -> %s\n", r.Name, shortHash(r.Before), shortHash(r.After))
+		}
+	}
+	return nil
+}
+
+// operation is an entry of operation journal that records
+// the refs changed by a dx command
+type operation struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Args    []string  `json:"args"`
+	// HeadBefore and HeadAfter are the symbolic ref of HEAD,
+	// or commit hash when HEAD is detached
+	HeadBefore string      `json:"head_before"`
+	HeadAfter  string      `json:"head_after"`
+	Refs       []refChange `json:"refs"`
+
+	before map[string]string
+}
+
+// refChange records the commit of ref before and after the operation,
+// empty commit means the ref doesn't exist
+type refChange struct {
+	Name   string `json:"name"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+func (op *operation) command() string {
+	return strings.Join(append([]string{op.Command}, op.Args...), " ")
+}
+
+// journaled wraps run to record the refs that it changed into operation journal
+func journaled(run func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		op, err := beginOperation(cmd, args)
+		if err != nil {
+			return err
+		}
+		runErr := run(cmd, args)
+		err = op.finish()
+		if err != nil {
+			slog.Warn("cannot record operation", "error", err)
+		}
+		return runErr
+	}
+}
+
+func beginOperation(cmd *cobra.Command, args []string) (*operation, error) {
+	op := &operation{
+		ID:      bson.NewObjectID().Hex(),
+		Time:    time.Now(),
+		Command: cmd.CommandPath(),
+	}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		op.Args = append(op.Args, fmt.Sprintf("--%s=%s", f.Name, f.Value))
+	})
+	op.Args = append(op.Args, args...)
+
+	var err error
+	op.HeadBefore, err = getHeadRef()
+	if err != nil {
+		return nil, err
+	}
+	op.before, err = getBranchRefs()
+	if err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// finish records the operation if it changed any ref
+func (op *operation) finish() error {
+	var err error
+	op.HeadAfter, err = getHeadRef()
+	if err != nil {
+		return err
+	}
+	after, err := getBranchRefs()
+	if err != nil {
+		return err
+	}
+	var names []string
+	for name := range op.before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := op.before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if op.before[name] != after[name] {
+			op.Refs = append(op.Refs, refChange{
+				Name:   name,
+				Before: op.before[name],
+				After:  after[name],
+			})
+		}
+	}
+	if len(op.Refs) == 0 && op.HeadBefore == op.HeadAfter {
+		return nil
+	}
+	return appendOperation(op)
+}
+
+func getOpLogPath() (string, error) {
+	dxDir, err := getDxDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dxDir, "oplog"), nil
+}
+
+func appendOperation(op *operation) error {
+	path, err := getOpLogPath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// readOperations returns the operations in the journal sorted by time asc
+func readOperations() ([]*operation, error) {
+	path, err := getOpLogPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ops []*operation
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		op := new(operation)
+		err = json.Unmarshal(scanner.Bytes(), op)
+		if err != nil {
+			return nil, fmt.Errorf("invalid operation journal %s: %w", path, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, scanner.Err()
+}
+
+// getBranchRefs returns map of branch ref name to its commit
+func getBranchRefs() (map[string]string, error) {
+	out, err := exec.OutputErr("git", "for-each-ref", "--format=%(refname) %(objectname)", "refs/heads")
+	if err != nil {
+		return nil, fmt.Errorf("error during list refs: %s: %w", out, err)
+	}
+	refs := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		name, hash, ok := strings.Cut(line, " ")
+		if ok {
+			refs[name] = hash
+		}
+	}
+	return refs, nil
+}
+
+// getHeadRef returns the branch ref that HEAD points to, or commit hash when HEAD is detached
+func getHeadRef() (string, error) {
+	out, err := exec.OutputErr("git", "symbolic-ref", "--quiet", "HEAD")
+	if err == nil {
+		return strings.TrimRight(out, "\n"), nil
+	}
+	out, err = exec.OutputErr("git", "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("error during get HEAD: %s: %w", out, err)
+	}
+	return strings.TrimRight(out, "\n"), nil
+}
+
+func shortRef(ref string) string {
+	if strings.HasPrefix(ref, "refs/heads/") {
+		return ref[len("refs/heads/"):]
+	}
+	return shortHash(ref)
+}
+
+func shortHash(hash string) string {
+	if hash == "" {
+		return "(none)"
+	}
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
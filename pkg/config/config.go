@@ -0,0 +1,341 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/exec"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// RepoFileName is the config file that committed at the repository root
+	RepoFileName = ".dx.yaml"
+
+	SourceDefault   = "default"
+	SourceGitConfig = "git config"
+
+	StrategySquash     = "squash"
+	StrategyCherryPick = "cherry-pick"
+
+	PolicyUnion       = "union"
+	PolicyOurs        = "ours"
+	PolicyTheirs      = "theirs"
+	PolicySortedUnion = "sorted-union"
+)
+
+var (
+	syncStrategies = []string{StrategySquash, StrategyCherryPick}
+	// Policies are the merge policies of resolvers.policy rules
+	Policies = []string{PolicyUnion, PolicyOurs, PolicyTheirs, PolicySortedUnion}
+)
+
+type Config struct {
+	// MainBranch is the branch that feature branches are based on,
+	// it's detected from the remote HEAD when empty
+	MainBranch string    `yaml:"mainBranch"`
+	Remote     string    `yaml:"remote"`
+	Sync       Sync      `yaml:"sync"`
+	Resolvers  Resolvers `yaml:"resolvers"`
+	Trailer    Trailer   `yaml:"trailer"`
+
+	// sources is map of config key to where its value came from
+	sources map[string]string
+}
+
+type Sync struct {
+	// Targets are the branches that allowed to sync into, any branch is allowed when empty
+	Targets  []string `yaml:"targets"`
+	Strategy string   `yaml:"strategy"`
+	// Verify are shell commands that must pass before the synced commits are committed
+	Verify []string `yaml:"verify"`
+}
+
+type Resolvers struct {
+	// Enabled are names of conflict resolvers to run, all resolvers run when empty
+	Enabled []string `yaml:"enabled"`
+	// Disabled are names of conflict resolvers to skip, including resolver plugins
+	Disabled []string `yaml:"disabled"`
+	// Order are names of conflict resolvers that run first in this order,
+	// the others run after them in the default order
+	Order []string `yaml:"order"`
+	// Plugins are names of resolver plugins in .dx/resolvers of the repository that allowed
+	// to run, the plugins are read from HEAD commit. the plugins on PATH are always allowed
+	Plugins []string `yaml:"plugins"`
+	// Generate are rules of generated files that regenerated instead of merged
+	Generate []GenerateRule `yaml:"generate"`
+	// Policy are rules of files that resolved by taking the hunks of one or both sides
+	Policy []PolicyRule `yaml:"policy"`
+	// Migrations are directories of numbered SQL migrations, e.g. 000123_add_users.up.sql,
+	// the incoming migrations that collide are renumbered on resolve-conflict and sync
+	Migrations []string `yaml:"migrations"`
+}
+
+// PolicyRule assigns the merge policy to the files, the first matched rule wins
+type PolicyRule struct {
+	// Paths are globs of the files, relative to the repository root
+	Paths []string `yaml:"paths"`
+	// Policy is one of union, ours, theirs and sorted-union
+	Policy string `yaml:"policy"`
+}
+
+// GenerateRule maps the generated files to the command that generates them
+type GenerateRule struct {
+	Name string `yaml:"name"`
+	// Outputs are globs of the generated files, relative to the repository root
+	Outputs []string `yaml:"outputs"`
+	// Inputs are globs of the source files, they must be resolved before the command runs
+	Inputs []string `yaml:"inputs"`
+	// Command is a shell command that runs at the repository root
+	Command string `yaml:"command"`
+}
+
+type Trailer struct {
+	// Key is the commit message trailer key of change id
+	Key string `yaml:"key"`
+}
+
+func Default() *Config {
+	c := &Config{
+		Remote: "origin",
+		Sync: Sync{
+			Strategy: StrategySquash,
+		},
+		Trailer: Trailer{
+			Key: "change-id",
+		},
+	}
+	c.sources = map[string]string{}
+	for _, f := range fields(c) {
+		if !f.value.IsZero() {
+			c.sources[f.key] = SourceDefault
+		}
+	}
+	return c
+}
+
+// Load merges the config in order of precedence, the later overrides the earlier
+//  1. default values
+//  2. .dx.yaml at the repository root
+//  3. user config file, see UserFilePath
+//  4. `git config dx.*` keys
+func Load(repoRoot string) (*Config, error) {
+	c := Default()
+
+	layers := []string{filepath.Join(repoRoot, RepoFileName)}
+	userFile, err := UserFilePath()
+	if err == nil {
+		layers = append(layers, userFile)
+	}
+	for _, path := range layers {
+		layer, err := readFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.merge(layer, path)
+	}
+
+	layer, err := readGitConfig()
+	if err != nil {
+		return nil, err
+	}
+	c.merge(layer, SourceGitConfig)
+
+	return c, c.validate()
+}
+
+// UserFilePath returns path of user config file, it's `$XDG_CONFIG_HOME/dx/config.yaml`
+// on Linux. See os.UserConfigDir
+func UserFilePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dx", "config.yaml"), nil
+}
+
+func readFile(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	err = dec.Decode(c)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// readGitConfig reads `dx.*` keys from git config, the key is case-insensitive
+// and list value is set by multiple keys
+//
+// ### Example output of `git config --get-regexp ^dx\.`
+//
+//	dx.mainbranch develop
+//	dx.sync.targets dev
+//	dx.sync.targets beta
+func readGitConfig() (*Config, error) {
+	out, err := exec.OutputErr("git", "config", "--get-regexp", `^dx\.`)
+	if err != nil {
+		// exit code 1 means no key is found
+		if out == "" {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("error during read git config: %s: %w", out, err)
+	}
+
+	c := &Config{}
+	fs := fields(c)
+	for _, line := range strings.Split(out, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		if key == "" {
+			continue
+		}
+		key = strings.TrimPrefix(key, "dx.")
+		i := slices.IndexFunc(fs, func(f field) bool {
+			return strings.EqualFold(f.key, key)
+		})
+		if i == -1 {
+			slog.Debug("ignore unknown git config", "key", "dx."+key)
+			continue
+		}
+		v := fs[i].value
+		switch v.Kind() {
+		case reflect.String:
+			v.SetString(value)
+		case reflect.Slice:
+			if v.Type().Elem().Kind() != reflect.String {
+				slog.Debug("git config is not supported for the key", "key", "dx."+key)
+				continue
+			}
+			v.Set(reflect.Append(v, reflect.ValueOf(value)))
+		default:
+			slog.Debug("git config is not supported for the key", "key", "dx."+key)
+		}
+	}
+	return c, nil
+}
+
+func (c *Config) merge(layer *Config, source string) {
+	dst := fields(c)
+	for i, f := range fields(layer) {
+		if f.value.IsZero() {
+			continue
+		}
+		dst[i].value.Set(f.value)
+		c.sources[f.key] = source
+	}
+}
+
+func (c *Config) validate() error {
+	if !slices.Contains(syncStrategies, c.Sync.Strategy) {
+		return fmt.Errorf("invalid sync.strategy %q from %s, must be one of %s",
+			c.Sync.Strategy, c.Source("sync.strategy"), strings.Join(syncStrategies, ", "))
+	}
+	if c.Remote == "" {
+		return errors.New("remote must not be empty")
+	}
+	if c.Trailer.Key == "" {
+		return errors.New("trailer.key must not be empty")
+	}
+	for i, r := range c.Resolvers.Generate {
+		if len(r.Outputs) == 0 || r.Command == "" {
+			return fmt.Errorf("resolvers.generate[%d] from %s must have outputs and command",
+				i, c.Source("resolvers.generate"))
+		}
+	}
+	for i, r := range c.Resolvers.Policy {
+		if len(r.Paths) == 0 {
+			return fmt.Errorf("resolvers.policy[%d] from %s must have paths", i, c.Source("resolvers.policy"))
+		}
+		if !slices.Contains(Policies, r.Policy) {
+			return fmt.Errorf("invalid resolvers.policy[%d].policy %q from %s, must be one of %s",
+				i, r.Policy, c.Source("resolvers.policy"), strings.Join(Policies, ", "))
+		}
+	}
+	return nil
+}
+
+// Source returns where the value of key came from
+func (c *Config) Source(key string) string {
+	if s, ok := c.sources[key]; ok {
+		return s
+	}
+	return SourceDefault
+}
+
+// Value is a config key with its effective value and source
+type Value struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// Values returns every config key in declaration order
+func (c *Config) Values() []Value {
+	var values []Value
+	for _, f := range fields(c) {
+		v := Value{
+			Key:    f.key,
+			Source: c.Source(f.key),
+		}
+		if f.value.IsZero() {
+			v.Source = "-"
+		}
+		switch f.value.Kind() {
+		case reflect.Slice:
+			var items []string
+			for i := 0; i < f.value.Len(); i++ {
+				items = append(items, fmt.Sprint(f.value.Index(i).Interface()))
+			}
+			v.Value = "[" + strings.Join(items, ", ") + "]"
+		default:
+			v.Value = fmt.Sprint(f.value.Interface())
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+type field struct {
+	key   string
+	value reflect.Value
+}
+
+// fields flattens the config struct into the leaf fields with their dotted yaml key
+func fields(c *Config) []field {
+	var fs []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+			key := prefix + name
+			if sf.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), key+".")
+				continue
+			}
+			fs = append(fs, field{key: key, value: v.Field(i)})
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+	return fs
+}
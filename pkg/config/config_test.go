@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newConfigTest(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, "gitconfig"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	repo := filepath.Join(dir, "repo")
+	require.NoError(t, os.Mkdir(repo, 0755))
+	out, err := exec.Command("git", "init", repo).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(repo))
+	t.Cleanup(func() {
+		err := os.Chdir(wd)
+		if err != nil {
+			panic(err)
+		}
+	})
+	return repo
+}
+
+func TestLoad_Default(t *testing.T) {
+	repo := newConfigTest(t)
+
+	c, err := Load(repo)
+	require.NoError(t, err)
+	assert.Equal(t, "origin", c.Remote)
+	assert.Equal(t, StrategySquash, c.Sync.Strategy)
+	assert.Equal(t, "change-id", c.Trailer.Key)
+	assert.Equal(t, SourceDefault, c.Source("remote"))
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	repo := newConfigTest(t)
+	err := os.WriteFile(filepath.Join(repo, RepoFileName), []byte(`mainBranch: develop
+remote: upstream
+sync:
+  targets: [dev, beta]
+  verify:
+    - go build ./...
+resolvers:
+  enabled: [go mod]
+  disabled: [json yaml]
+  order: [terraform, go mod]
+  plugins: [terraform]
+  generate:
+    - name: protobuf
+      outputs: ["**/*.pb.go"]
+      inputs: ["proto/**/*.proto"]
+      command: buf generate
+  policy:
+    - paths: [CHANGELOG.md]
+      policy: union
+  migrations: [db/migrations]
+`), 0644)
+	require.NoError(t, err)
+
+	userFile, err := UserFilePath()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(userFile), 0755))
+	err = os.WriteFile(userFile, []byte(`remote: fork
+sync:
+  strategy: cherry-pick
+`), 0644)
+	require.NoError(t, err)
+
+	for _, args := range [][]string{
+		{"config", "dx.mainBranch", "trunk"},
+		{"config", "--add", "dx.sync.targets", "dev"},
+		{"config", "--add", "dx.sync.targets", "staging"},
+		{"config", "dx.unknown", "value"},
+	} {
+		out, err := exec.Command("git", args...).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	c, err := Load(repo)
+	require.NoError(t, err)
+	assert.Equal(t, "trunk", c.MainBranch)
+	assert.Equal(t, SourceGitConfig, c.Source("mainBranch"))
+	assert.Equal(t, "fork", c.Remote)
+	assert.Equal(t, userFile, c.Source("remote"))
+	assert.Equal(t, []string{"dev", "staging"}, c.Sync.Targets)
+	assert.Equal(t, StrategyCherryPick, c.Sync.Strategy)
+	assert.Equal(t, []string{"go build ./..."}, c.Sync.Verify)
+	assert.Equal(t, filepath.Join(repo, RepoFileName), c.Source("sync.verify"))
+	assert.Equal(t, []string{"go mod"}, c.Resolvers.Enabled)
+	assert.Equal(t, []string{"json yaml"}, c.Resolvers.Disabled)
+	assert.Equal(t, []string{"terraform", "go mod"}, c.Resolvers.Order)
+	assert.Equal(t, []string{"terraform"}, c.Resolvers.Plugins)
+	assert.Equal(t, []GenerateRule{{
+		Name:    "protobuf",
+		Outputs: []string{"**/*.pb.go"},
+		Inputs:  []string{"proto/**/*.proto"},
+		Command: "buf generate",
+	}}, c.Resolvers.Generate)
+	assert.Equal(t, []PolicyRule{{Paths: []string{"CHANGELOG.md"}, Policy: PolicyUnion}}, c.Resolvers.Policy)
+	assert.Equal(t, []string{"db/migrations"}, c.Resolvers.Migrations)
+	assert.Equal(t, "change-id", c.Trailer.Key)
+
+	values := c.Values()
+	assert.Equal(t, Value{Key: "mainBranch", Value: "trunk", Source: SourceGitConfig}, values[0])
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	repo := newConfigTest(t)
+	err := os.WriteFile(filepath.Join(repo, RepoFileName), []byte("unknown: value\n"), 0644)
+	require.NoError(t, err)
+	_, err = Load(repo)
+	assert.ErrorContains(t, err, "invalid config file")
+
+	err = os.WriteFile(filepath.Join(repo, RepoFileName), []byte("sync:\n  strategy: rebase\n"), 0644)
+	require.NoError(t, err)
+	_, err = Load(repo)
+	assert.ErrorContains(t, err, `invalid sync.strategy "rebase"`)
+
+	err = os.WriteFile(filepath.Join(repo, RepoFileName), []byte("resolvers:\n  generate:\n    - outputs: [\"*.pb.go\"]\n"), 0644)
+	require.NoError(t, err)
+	_, err = Load(repo)
+	assert.ErrorContains(t, err, "resolvers.generate[0]")
+
+	err = os.WriteFile(filepath.Join(repo, RepoFileName), []byte("resolvers:\n  policy:\n    - paths: [\"*.md\"]\n      policy: mine\n"), 0644)
+	require.NoError(t, err)
+	_, err = Load(repo)
+	assert.ErrorContains(t, err, `invalid resolvers.policy[0].policy "mine"`)
+}
@@ -0,0 +1,285 @@
+// Package conflictfile parses the files that git left conflict markers in,
+// it supports merge, diff3 and zdiff3 conflict styles and custom marker size.
+//
+// ### Example content with diff3 conflict style
+//
+//	<<<<<<< HEAD
+//	ours
+//	||||||| base
+//	base
+//	=======
+//	theirs
+//	>>>>>>> feature
+package conflictfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/exec"
+)
+
+// DefaultMarkerSize is the marker size that git uses when
+// `conflict-marker-size` attribute is not set
+const DefaultMarkerSize = 7
+
+// Hunk is a conflicted part of file. lines keep their line ending,
+// so the resolved content can be written back as it is
+type Hunk struct {
+	OursLabel   string
+	BaseLabel   string
+	TheirsLabel string
+
+	Ours []string
+	// Base is only set when the file uses diff3 or zdiff3 conflict style
+	Base   []string
+	Theirs []string
+	// HasBase is true if the hunk has base section, base might be empty
+	HasBase bool
+}
+
+// Chunk is either lines that are not conflicted or a conflict hunk
+type Chunk struct {
+	Lines []string
+	Hunk  *Hunk
+}
+
+type File struct {
+	Chunks     []Chunk
+	MarkerSize int
+}
+
+// Resolution returns the lines that replace the hunk
+type Resolution func(h *Hunk) []string
+
+var (
+	Ours   Resolution = func(h *Hunk) []string { return h.Ours }
+	Theirs Resolution = func(h *Hunk) []string { return h.Theirs }
+	// Union keeps lines of both sides, ours comes first. the base is dropped
+	Union Resolution = func(h *Hunk) []string {
+		return append(append([]string{}, h.Ours...), h.Theirs...)
+	}
+	// SortedUnion keeps unique lines of both sides in sorted order
+	SortedUnion Resolution = func(h *Hunk) []string {
+		lines := Union(h)
+		slices.Sort(lines)
+		return slices.Compact(lines)
+	}
+)
+
+// ReadFile parses the file with marker size from its git attributes
+func ReadFile(path string) (*File, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	size, err := MarkerSize(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b, size)
+}
+
+// IsConflicted returns true if the file still has conflict markers,
+// malformed markers are counted as conflicted
+func IsConflicted(path string) (bool, error) {
+	f, err := ReadFile(path)
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return f.HasConflict(), nil
+}
+
+// MarkerSize returns `conflict-marker-size` attribute of path
+//
+// ### Example output of `git check-attr conflict-marker-size -- CHANGELOG.md`
+//
+//	CHANGELOG.md: conflict-marker-size: 32
+func MarkerSize(path string) (int, error) {
+	out, err := exec.OutputErrDir(filepath.Dir(path), "git", "check-attr", "conflict-marker-size", "--", filepath.Base(path))
+	if err != nil {
+		return 0, fmt.Errorf("error during check conflict-marker-size of %s: %s: %w", path, out, err)
+	}
+	i := strings.LastIndex(out, ": ")
+	if i == -1 {
+		return DefaultMarkerSize, nil
+	}
+	size, err := strconv.Atoi(strings.TrimSpace(out[i+2:]))
+	if err != nil || size <= 0 {
+		// unspecified, unset or invalid value
+		return DefaultMarkerSize, nil
+	}
+	return size, nil
+}
+
+type ParseError struct {
+	Line    int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+type section int
+
+const (
+	sectionNone section = iota
+	sectionOurs
+	sectionBase
+	sectionTheirs
+)
+
+// Parse splits content into chunks by conflict markers of markerSize
+func Parse(b []byte, markerSize int) (*File, error) {
+	var (
+		oursMarker   = strings.Repeat("<", markerSize)
+		baseMarker   = strings.Repeat("|", markerSize)
+		sepMarker    = strings.Repeat("=", markerSize)
+		theirsMarker = strings.Repeat(">", markerSize)
+	)
+	f := &File{MarkerSize: markerSize}
+	var lines []string
+	var hunk *Hunk
+	var hunkStart int
+	current := sectionNone
+
+	for i, line := range strings.SplitAfter(string(b), "\n") {
+		if line == "" {
+			continue
+		}
+		n := i + 1
+		label, isOurs := cutMarker(line, oursMarker)
+		if isOurs {
+			if current != sectionNone {
+				return nil, &ParseError{Line: n, Message: "nested conflict marker"}
+			}
+			if len(lines) != 0 {
+				f.Chunks = append(f.Chunks, Chunk{Lines: lines})
+				lines = nil
+			}
+			hunk = &Hunk{OursLabel: label}
+			hunkStart = n
+			current = sectionOurs
+			continue
+		}
+		if current == sectionNone {
+			lines = append(lines, line)
+			continue
+		}
+		if label, ok := cutMarker(line, baseMarker); ok && current == sectionOurs {
+			hunk.BaseLabel = label
+			hunk.HasBase = true
+			current = sectionBase
+			continue
+		}
+		if _, ok := cutMarker(line, sepMarker); ok && current != sectionTheirs {
+			current = sectionTheirs
+			continue
+		}
+		if label, ok := cutMarker(line, theirsMarker); ok && current == sectionTheirs {
+			hunk.TheirsLabel = label
+			f.Chunks = append(f.Chunks, Chunk{Hunk: hunk})
+			hunk = nil
+			current = sectionNone
+			continue
+		}
+		switch current {
+		case sectionOurs:
+			hunk.Ours = append(hunk.Ours, line)
+		case sectionBase:
+			hunk.Base = append(hunk.Base, line)
+		case sectionTheirs:
+			hunk.Theirs = append(hunk.Theirs, line)
+		}
+	}
+	if current != sectionNone {
+		return nil, &ParseError{Line: hunkStart, Message: "conflict marker is not closed"}
+	}
+	if len(lines) != 0 {
+		f.Chunks = append(f.Chunks, Chunk{Lines: lines})
+	}
+	return f, nil
+}
+
+// cutMarker returns the label after marker if line is the marker line
+func cutMarker(line, marker string) (string, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	if line == marker {
+		return "", true
+	}
+	label, ok := strings.CutPrefix(line, marker+" ")
+	return label, ok
+}
+
+func (f *File) HasConflict() bool {
+	return len(f.Hunks()) != 0
+}
+
+func (f *File) Hunks() []*Hunk {
+	var hunks []*Hunk
+	for _, c := range f.Chunks {
+		if c.Hunk != nil {
+			hunks = append(hunks, c.Hunk)
+		}
+	}
+	return hunks
+}
+
+// Resolve returns the content that every hunk is replaced by r
+func (f *File) Resolve(r Resolution) []byte {
+	var sb strings.Builder
+	for _, c := range f.Chunks {
+		lines := c.Lines
+		if c.Hunk != nil {
+			lines = r(c.Hunk)
+		}
+		for _, l := range lines {
+			sb.WriteString(l)
+		}
+	}
+	return []byte(sb.String())
+}
+
+// Bytes returns the content with conflict markers, it's the same as the parsed content
+func (f *File) Bytes() []byte {
+	return f.Resolve(f.markHunk)
+}
+
+func (f *File) markHunk(h *Hunk) []string {
+	marker := func(c byte, label string) string {
+		m := strings.Repeat(string(c), f.MarkerSize)
+		if label != "" {
+			m += " " + label
+		}
+		return m + "\n"
+	}
+	lines := []string{marker('<', h.OursLabel)}
+	lines = append(lines, h.Ours...)
+	if h.HasBase {
+		lines = append(lines, marker('|', h.BaseLabel))
+		lines = append(lines, h.Base...)
+	}
+	lines = append(lines, marker('=', ""))
+	lines = append(lines, h.Theirs...)
+	lines = append(lines, marker('>', h.TheirsLabel))
+	return lines
+}
+
+// WriteFile writes the content that every hunk is replaced by r into path
+func (f *File) WriteFile(path string, r Resolution) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, f.Resolve(r), info.Mode().Perm())
+}
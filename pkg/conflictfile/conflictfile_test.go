@@ -0,0 +1,122 @@
+package conflictfile
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Merge(t *testing.T) {
+	content := `module test
+
+<<<<<<< HEAD
+require a v1.0.0
+=======
+require b v1.0.0
+>>>>>>> feature
+`
+	f, err := Parse([]byte(content), DefaultMarkerSize)
+	require.NoError(t, err)
+	require.True(t, f.HasConflict())
+	hunks := f.Hunks()
+	require.Len(t, hunks, 1)
+	assert.Equal(t, &Hunk{
+		OursLabel:   "HEAD",
+		TheirsLabel: "feature",
+		Ours:        []string{"require a v1.0.0\n"},
+		Theirs:      []string{"require b v1.0.0\n"},
+	}, hunks[0])
+	assert.Equal(t, "module test\n\nrequire a v1.0.0\nrequire b v1.0.0\n", string(f.Resolve(Union)))
+	assert.Equal(t, "module test\n\nrequire b v1.0.0\n", string(f.Resolve(Theirs)))
+	assert.Equal(t, content, string(f.Bytes()))
+}
+
+func TestParse_Diff3(t *testing.T) {
+	content := `a
+<<<<<<< HEAD
+ours
+||||||| merged common ancestors
+base
+=======
+theirs
+>>>>>>> feature
+b
+<<<<<<< HEAD
+||||||| base
+=======
+added
+>>>>>>> feature
+`
+	f, err := Parse([]byte(content), DefaultMarkerSize)
+	require.NoError(t, err)
+	hunks := f.Hunks()
+	require.Len(t, hunks, 2)
+	assert.True(t, hunks[0].HasBase)
+	assert.Equal(t, "merged common ancestors", hunks[0].BaseLabel)
+	assert.Equal(t, []string{"base\n"}, hunks[0].Base)
+	assert.True(t, hunks[1].HasBase)
+	assert.Empty(t, hunks[1].Ours)
+	assert.Empty(t, hunks[1].Base)
+	assert.Equal(t, "a\nours\ntheirs\nb\nadded\n", string(f.Resolve(Union)))
+	assert.Equal(t, content, string(f.Bytes()))
+}
+
+func TestSortedUnion(t *testing.T) {
+	f, err := Parse([]byte(`# owners
+<<<<<<< HEAD
+/web @web
+/api @api
+=======
+/api @api
+/cli @cli
+>>>>>>> feature
+`), DefaultMarkerSize)
+	require.NoError(t, err)
+	assert.Equal(t, "# owners\n/api @api\n/cli @cli\n/web @web\n", string(f.Resolve(SortedUnion)))
+}
+
+func TestParse_MarkerSize(t *testing.T) {
+	content := `<<<<<<<<<<<<<<<< HEAD
+<<<<<<< not a marker
+ours
+================
+=======
+theirs
+>>>>>>>>>>>>>>>> feature
+`
+	f, err := Parse([]byte(content), 16)
+	require.NoError(t, err)
+	assert.Equal(t, "<<<<<<< not a marker\nours\n=======\ntheirs\n", string(f.Resolve(Union)))
+
+	_, err = Parse([]byte(content), DefaultMarkerSize)
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, 2, parseErr.Line)
+}
+
+func TestParse_NoConflict(t *testing.T) {
+	content := "title\n=======\n\nno newline at the end"
+	f, err := Parse([]byte(content), DefaultMarkerSize)
+	require.NoError(t, err)
+	assert.False(t, f.HasConflict())
+	assert.Equal(t, content, string(f.Bytes()))
+}
+
+func TestMarkerSize(t *testing.T) {
+	dir := t.TempDir()
+	out, err := exec.Command("git", "init", dir).CombinedOutput()
+	require.NoError(t, err, string(out))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitattributes"), []byte("*.md conflict-marker-size=32\n"), 0644))
+
+	size, err := MarkerSize(filepath.Join(dir, "CHANGELOG.md"))
+	require.NoError(t, err)
+	assert.Equal(t, 32, size)
+
+	size, err = MarkerSize(filepath.Join(dir, "go.sum"))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMarkerSize, size)
+}
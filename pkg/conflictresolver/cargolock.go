@@ -0,0 +1,188 @@
+package conflictresolver
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/conflictfile"
+	"github.com/kitimark/dx/pkg/exec"
+)
+
+type CargoLockResolver struct {
+	offline bool
+}
+
+var (
+	cargoLockFileName = "Cargo.lock"
+	cargoTomlFileName = "Cargo.toml"
+)
+
+func (r *CargoLockResolver) Name() string {
+	return "cargo lock"
+}
+
+// SetOffline makes the resolver update the lockfile with the local registry cache
+func (r *CargoLockResolver) SetOffline(offline bool) {
+	r.offline = offline
+}
+
+func (r *CargoLockResolver) Detect(files []ConflictedFile) bool {
+	return len(dirsOf(files, cargoLockFileName)) != 0
+}
+
+// Resolve updates Cargo.lock of ours in each directory that it's conflicted, Cargo.toml
+// of the workspace and its members must be resolved first
+func (r *CargoLockResolver) Resolve(files []ConflictedFile) error {
+	for _, dir := range dirsOf(files, cargoLockFileName) {
+		manifests, err := findCargoManifests(dir)
+		if err != nil {
+			return err
+		}
+		var stillConflictedFiles []string
+		for _, m := range manifests {
+			isStillConflict, err := conflictfile.IsConflicted(m)
+			if err != nil {
+				return err
+			}
+			if isStillConflict {
+				stillConflictedFiles = append(stillConflictedFiles, m)
+			}
+		}
+		if len(stillConflictedFiles) != 0 {
+			return fmt.Errorf(`Cargo.toml files still conflicted, resolve them first
+%s`, strings.Join(stillConflictedFiles, "\n"))
+		}
+
+		// cargo cannot read the lockfile with conflict markers, start from ours
+		lockFile := filepath.Join(dir, cargoLockFileName)
+		b, err := readOurs(lockFile)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(lockFile, b, 0644)
+		if err != nil {
+			return err
+		}
+
+		// `cargo generate-lockfile` would bump every dependency, only the workspace
+		// members and the missing dependencies are updated on top of ours
+		args := []string{"update", "--workspace"}
+		if r.offline {
+			args = append(args, "--offline")
+		}
+		slog.Info("run cargo "+strings.Join(args, " "), "dir", dir)
+		out, err := exec.OutputErrDir(dir, "cargo", args...)
+		if err != nil {
+			slog.Error(out)
+			return err
+		}
+	}
+	return nil
+}
+
+// findCargoManifests returns Cargo.toml of the workspace in dir, every crate under it and
+// the workspace members, the members might be outside dir. the crates under dir that have
+// their own Cargo.lock are separated workspaces so they're skipped
+func findCargoManifests(dir string) ([]string, error) {
+	var manifests []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			if d.Name() == cargoTomlFileName {
+				manifests = append(manifests, path)
+			}
+			return nil
+		}
+		if path == dir {
+			return nil
+		}
+		if d.Name() == "target" || strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, cargoLockFileName)); err == nil {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, cargoTomlFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifests, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range cargoWorkspaceMembers(b) {
+		paths, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(member), cargoTomlFileName))
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspace member %q of %s: %w", member, dir, err)
+		}
+		for _, path := range paths {
+			if !slices.Contains(manifests, path) {
+				manifests = append(manifests, path)
+			}
+		}
+	}
+	return manifests, nil
+}
+
+var cargoQuotedRegex = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+
+// cargoWorkspaceMembers returns the globs of `members` in `[workspace]` table of Cargo.toml,
+// the array might span multiple lines
+//
+// ### Example Cargo.toml
+//
+//	[workspace]
+//	members = [
+//	    "crates/*",
+//	    "../libs/shared", # shared with the other services
+//	]
+func cargoWorkspaceMembers(b []byte) []string {
+	var members []string
+	inWorkspace, inMembers := false, false
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(line, "#"); i != -1 && !strings.ContainsAny(line[:i], `"'`) {
+			line = strings.TrimSpace(line[:i])
+		}
+		if !inMembers && strings.HasPrefix(line, "[") {
+			inWorkspace = line == "[workspace]"
+			continue
+		}
+		if !inWorkspace {
+			continue
+		}
+		if !inMembers {
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || strings.TrimSpace(key) != "members" {
+				continue
+			}
+			line = strings.TrimSpace(value)
+			if !strings.HasPrefix(line, "[") {
+				continue
+			}
+			inMembers = true
+		}
+		value, _, closed := strings.Cut(line, "]")
+		for _, m := range cargoQuotedRegex.FindAllStringSubmatch(value, -1) {
+			members = append(members, m[1]+m[2])
+		}
+		if closed {
+			inMembers = false
+		}
+	}
+	return members
+}
@@ -0,0 +1,28 @@
+package conflictresolver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCargoWorkspaceMembers(t *testing.T) {
+	assert.Equal(t, []string{"crates/*", "../libs/shared", "tools/cli"}, cargoWorkspaceMembers([]byte(`[package]
+name = "api"
+members = ["not/a/member"]
+
+[workspace]
+resolver = "2"
+members = [
+    "crates/*",
+    "../libs/shared", # shared with the other services
+    'tools/cli',
+]
+exclude = ["crates/legacy"]
+
+[workspace.dependencies]
+serde = "1"
+`)))
+	assert.Equal(t, []string{"a", "b"}, cargoWorkspaceMembers([]byte("[workspace]\nmembers = [\"a\", \"b\"]\n")))
+	assert.Empty(t, cargoWorkspaceMembers([]byte("[package]\nname = \"core\"\n")))
+}
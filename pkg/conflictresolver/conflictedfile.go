@@ -0,0 +1,73 @@
+package conflictresolver
+
+import "slices"
+
+// ConflictKind is the unmerged state of conflicted file, the value is XY status of `git status`
+//
+// ref: https://git-scm.com/docs/git-status#_short_format
+type ConflictKind string
+
+const (
+	BothDeleted   ConflictKind = "DD"
+	AddedByUs     ConflictKind = "AU"
+	DeletedByThem ConflictKind = "UD"
+	AddedByThem   ConflictKind = "UA"
+	DeletedByUs   ConflictKind = "DU"
+	BothAdded     ConflictKind = "AA"
+	BothModified  ConflictKind = "UU"
+)
+
+var conflictKindNames = map[ConflictKind]string{
+	BothDeleted:   "both deleted",
+	AddedByUs:     "added by us",
+	DeletedByThem: "deleted by them",
+	AddedByThem:   "added by them",
+	DeletedByUs:   "deleted by us",
+	BothAdded:     "both added",
+	BothModified:  "both modified",
+}
+
+// IsConflictKind returns true if XY status is one of unmerged states
+func IsConflictKind(xy string) bool {
+	_, ok := conflictKindNames[ConflictKind(xy)]
+	return ok
+}
+
+func (k ConflictKind) String() string {
+	if name, ok := conflictKindNames[k]; ok {
+		return name
+	}
+	return string(k)
+}
+
+// IsDeleted returns true if the file is deleted by any side
+func (k ConflictKind) IsDeleted() bool {
+	return k == BothDeleted || k == DeletedByThem || k == DeletedByUs
+}
+
+// gitlinkMode is the index mode of submodule
+const gitlinkMode = "160000"
+
+type ConflictedFile struct {
+	// Path is relative to the repository root
+	Path string
+	Kind ConflictKind
+	// Modes and Hashes are the index entries of base, ours and theirs stage,
+	// the mode is 000000 when the stage doesn't exist
+	Modes  [3]string
+	Hashes [3]string
+}
+
+// IsGitlink returns true if any side of the conflicted path is a submodule
+func (f ConflictedFile) IsGitlink() bool {
+	return slices.Contains(f.Modes[:], gitlinkMode)
+}
+
+// Paths returns path of each conflicted file
+func Paths(files []ConflictedFile) []string {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	return paths
+}
@@ -2,11 +2,32 @@ package conflictresolver
 
 type ConflictResolver interface {
 	Name() string
-	Detect(fileNames []string) bool
-	Resolve(fileNames []string) error
+	Detect(files []ConflictedFile) bool
+	Resolve(files []ConflictedFile) error
+}
+
+// OfflineResolver is a resolver that resolves differently without network access,
+// offline mode is set before every run
+type OfflineResolver interface {
+	SetOffline(offline bool)
+}
+
+// NetworkResolver is a resolver that may need network access, it's skipped in offline
+// mode unless it's an OfflineResolver. the other resolvers run as they are
+type NetworkResolver interface {
+	NeedsNetwork() bool
 }
 
 var ConflictResolvers = []ConflictResolver{
+	&SubmoduleResolver{},
+	&GoImportResolver{},
+	&StructuredResolver{},
 	&GoModResolver{},
 	&YarnLockResolver{},
+	&PackageLockResolver{},
+	&PnpmLockResolver{},
+	&CargoLockResolver{},
+	PoetryLockResolver,
+	UvLockResolver,
+	PipenvLockResolver,
 }
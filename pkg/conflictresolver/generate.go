@@ -0,0 +1,135 @@
+package conflictresolver
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/conflictfile"
+	"github.com/kitimark/dx/pkg/exec"
+)
+
+// GenerateRule maps globs of the generated files to the command that generates them,
+// the globs are slash separated and relative to the repository root
+type GenerateRule struct {
+	Name    string
+	Outputs []string
+	Inputs  []string
+	Command string
+}
+
+// GenerateResolver regenerates the conflicted generated files instead of merging them
+type GenerateResolver struct {
+	Rules []GenerateRule
+}
+
+func (r *GenerateResolver) Name() string {
+	return "generate"
+}
+
+// NeedsNetwork is true, the commands might download their plugins or dependencies
+func (r *GenerateResolver) NeedsNetwork() bool {
+	return true
+}
+
+func (r *GenerateResolver) Detect(files []ConflictedFile) bool {
+	for _, rule := range r.Rules {
+		if len(rule.outputsOf(files)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve runs the command of each rule that its outputs are conflicted, the inputs
+// must be resolved first. the outputs are left as they are, so the outputs that the
+// command doesn't write are still conflicted and reported
+func (r *GenerateResolver) Resolve(files []ConflictedFile) error {
+	for _, rule := range r.Rules {
+		outputs := rule.outputsOf(files)
+		if len(outputs) == 0 {
+			continue
+		}
+		err := rule.checkInputsConflictedIsResolved()
+		if err != nil {
+			return err
+		}
+
+		slog.Info("run generate command", "rule", rule.Name, "command", rule.Command)
+		out, err := exec.OutputErr("sh", "-c", rule.Command)
+		if err != nil {
+			return fmt.Errorf("generate command %q failed: %s: %w", rule.Command, out, err)
+		}
+
+		var stillConflictedFiles []string
+		for _, f := range outputs {
+			isStillConflict, err := conflictfile.IsConflicted(f.Path)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if isStillConflict {
+				stillConflictedFiles = append(stillConflictedFiles, f.Path)
+			}
+		}
+		if len(stillConflictedFiles) != 0 {
+			return fmt.Errorf(`generated files still conflicted after %q, check outputs of %s or resolve them manually
+%s`, rule.Command, rule.Name, strings.Join(stillConflictedFiles, "\n"))
+		}
+	}
+	return nil
+}
+
+func (rule GenerateRule) outputsOf(files []ConflictedFile) []ConflictedFile {
+	var outputs []ConflictedFile
+	for _, f := range files {
+		if !f.IsGitlink() && matchAnyGlob(rule.Outputs, filepath.ToSlash(f.Path)) {
+			outputs = append(outputs, f)
+		}
+	}
+	return outputs
+}
+
+// checkInputsConflictedIsResolved checks every tracked file that matches the inputs
+//
+// ### Example output of `git ls-files -z`
+//
+//	go.mod<NUL>proto/user.proto<NUL>proto/user.proto<NUL>
+func (rule GenerateRule) checkInputsConflictedIsResolved() error {
+	if len(rule.Inputs) == 0 {
+		return nil
+	}
+	out, err := exec.OutputErr("git", "ls-files", "-z")
+	if err != nil {
+		return fmt.Errorf("error during list files: %s: %w", out, err)
+	}
+	var stillConflictedFiles []string
+	for _, path := range strings.Split(out, "\x00") {
+		// unmerged file is listed once per stage
+		if path == "" || slices.Contains(stillConflictedFiles, path) ||
+			!matchAnyGlob(rule.Inputs, path) {
+			continue
+		}
+		isStillConflict, err := conflictfile.IsConflicted(filepath.FromSlash(path))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if isStillConflict {
+			stillConflictedFiles = append(stillConflictedFiles, path)
+		}
+	}
+	if len(stillConflictedFiles) != 0 {
+		return fmt.Errorf(`inputs of %s still conflicted, resolve them first
+%s`, rule.Name, strings.Join(stillConflictedFiles, "\n"))
+	}
+	return nil
+}
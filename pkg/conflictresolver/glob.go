@@ -0,0 +1,43 @@
+package conflictresolver
+
+import (
+	"path"
+	"strings"
+)
+
+// matchGlob reports whether slash separated name matches pattern, the pattern is
+// the same as path.Match except `**` segment matches zero or more directories
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if matchGlob(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(patterns, names []string) bool {
+	for len(patterns) != 0 {
+		if patterns[0] == "**" {
+			for i := 0; i <= len(names); i++ {
+				if matchSegments(patterns[1:], names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		ok, err := path.Match(patterns[0], names[0])
+		if err != nil || !ok {
+			return false
+		}
+		patterns, names = patterns[1:], names[1:]
+	}
+	return len(names) == 0
+}
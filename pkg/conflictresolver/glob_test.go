@@ -0,0 +1,28 @@
+package conflictresolver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"packages/*", "packages/web", true},
+		{"packages/*", "packages/web/nested", false},
+		{"packages/**", "packages/web/nested", true},
+		{"packages/**", "packages", true},
+		{"**/test/**", "packages/web/test/fixture", true},
+		{"**/*.sql", "migrations/001_init.sql", true},
+		{"**/*.sql", "001_init.sql", true},
+		{"apps/*", "packages/web", false},
+		{"[", "[", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchGlob(tt.pattern, tt.name), "%s %s", tt.pattern, tt.name)
+	}
+}
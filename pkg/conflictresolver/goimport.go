@@ -0,0 +1,247 @@
+package conflictresolver
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kitimark/dx/pkg/conflictfile"
+)
+
+// GoImportResolver resolves go files that only conflicted in import declarations,
+// the imports of both sides are kept and the unused ones are dropped
+type GoImportResolver struct{}
+
+func (r *GoImportResolver) Name() string {
+	return "go import"
+}
+
+func (r *GoImportResolver) Detect(files []ConflictedFile) bool {
+	for _, f := range goFilesOf(files) {
+		cf, err := conflictfile.ReadFile(f.Path)
+		if err != nil {
+			continue
+		}
+		if cf.HasConflict() && isImportOnlyConflict(cf) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve rewrites the go files that every hunk is in import declarations,
+// the files that conflicted in anything else are left for human
+func (r *GoImportResolver) Resolve(files []ConflictedFile) error {
+	for _, f := range goFilesOf(files) {
+		cf, err := conflictfile.ReadFile(f.Path)
+		var parseErr *conflictfile.ParseError
+		if errors.As(err, &parseErr) {
+			slog.Info("skip go file with malformed conflict markers", "file", f.Path, "error", err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !cf.HasConflict() {
+			continue
+		}
+		if !isImportOnlyConflict(cf) {
+			slog.Info("go file conflicted outside import declarations, resolve it manually", "file", f.Path)
+			continue
+		}
+		b, err := mergeGoImports(cf)
+		if err != nil {
+			return fmt.Errorf("cannot merge imports of %s: %w", f.Path, err)
+		}
+		err = os.WriteFile(f.Path, b, 0644)
+		if err != nil {
+			return err
+		}
+		slog.Info("merge imports of go file", "file", f.Path)
+	}
+	return nil
+}
+
+// goFilesOf returns the conflicted go files that both sides still have,
+// the vendored files are regenerated by the go mod resolver
+func goFilesOf(files []ConflictedFile) []ConflictedFile {
+	var goFiles []ConflictedFile
+	for _, f := range files {
+		if !strings.HasSuffix(f.Path, ".go") || f.Kind.IsDeleted() {
+			continue
+		}
+		if _, vendored := goVendorRoot(f.Path); vendored {
+			continue
+		}
+		goFiles = append(goFiles, f)
+	}
+	return goFiles
+}
+
+// isImportOnlyConflict returns true if every non-blank line of each side of
+// every hunk is in the import declarations of that side
+func isImportOnlyConflict(cf *conflictfile.File) bool {
+	for _, side := range []conflictfile.Resolution{conflictfile.Ours, conflictfile.Theirs} {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "", cf.Resolve(side), parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			return false
+		}
+		inImportDecl := func(line int) bool {
+			for _, d := range f.Decls {
+				d, ok := d.(*ast.GenDecl)
+				if !ok || d.Tok != token.IMPORT {
+					continue
+				}
+				if fset.Position(d.Pos()).Line <= line && line <= fset.Position(d.End()).Line {
+					return true
+				}
+			}
+			return false
+		}
+
+		line := 1
+		for _, c := range cf.Chunks {
+			if c.Hunk == nil {
+				line += len(c.Lines)
+				continue
+			}
+			for _, l := range side(c.Hunk) {
+				if strings.TrimSpace(l) != "" && !inImportDecl(line) {
+					return false
+				}
+				line++
+			}
+		}
+	}
+	return true
+}
+
+// mergeGoImports keeps the imports of both sides and gofmt the result. the import
+// that only one side has is dropped when it's no longer used, the name of import
+// is assumed from its path when it's not named, like goimports does
+func mergeGoImports(cf *conflictfile.File) ([]byte, error) {
+	var sides [2]map[string]bool
+	for i, side := range []conflictfile.Resolution{conflictfile.Ours, conflictfile.Theirs} {
+		f, err := parser.ParseFile(token.NewFileSet(), "", cf.Resolve(side), parser.ImportsOnly)
+		if err != nil {
+			return nil, err
+		}
+		sides[i] = map[string]bool{}
+		for _, spec := range f.Imports {
+			sides[i][importKey(spec)] = true
+		}
+	}
+
+	src := cf.Resolve(conflictfile.Union)
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	// the identifiers that declared in the file, e.g. local variables that shadow
+	// the import name, are resolved by the parser, only the unresolved ones might be packages
+	used := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Obj == nil {
+				used[x.Name] = true
+			}
+		}
+		return true
+	})
+
+	dropLines := map[int]bool{}
+	keepLines := map[int]bool{}
+	markLines := func(lines map[int]bool, from, to token.Pos) {
+		for l := fset.Position(from).Line; l <= fset.Position(to).Line; l++ {
+			lines[l] = true
+		}
+	}
+	seen := map[string]bool{}
+	for _, d := range f.Decls {
+		d, ok := d.(*ast.GenDecl)
+		if !ok || d.Tok != token.IMPORT {
+			continue
+		}
+		kept := 0
+		for _, s := range d.Specs {
+			spec := s.(*ast.ImportSpec)
+			key := importKey(spec)
+			name := importName(spec)
+			unused := name != "_" && name != "." && name != "C" && !used[name] &&
+				!(sides[0][key] && sides[1][key])
+			from, to := spec.Pos(), spec.End()
+			if spec.Doc != nil {
+				from = spec.Doc.Pos()
+			}
+			if spec.Comment != nil {
+				to = spec.Comment.End()
+			}
+			if seen[key] || unused {
+				markLines(dropLines, from, to)
+				continue
+			}
+			seen[key] = true
+			kept++
+			markLines(keepLines, from, to)
+		}
+		if kept == 0 {
+			from := d.Pos()
+			if d.Doc != nil {
+				from = d.Doc.Pos()
+			}
+			markLines(dropLines, from, d.End())
+		}
+	}
+
+	var b strings.Builder
+	for i, l := range strings.SplitAfter(string(src), "\n") {
+		if dropLines[i+1] && !keepLines[i+1] {
+			continue
+		}
+		b.WriteString(l)
+	}
+	return format.Source([]byte(b.String()))
+}
+
+func importKey(spec *ast.ImportSpec) string {
+	p, _ := strconv.Unquote(spec.Path.Value)
+	if spec.Name != nil {
+		return spec.Name.Name + " " + p
+	}
+	return p
+}
+
+// importName returns the name that the import is referred by
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	p, _ := strconv.Unquote(spec.Path.Value)
+	base := path.Base(p)
+	// major version suffix, e.g. github.com/go-chi/chi/v5
+	if strings.HasPrefix(base, "v") {
+		if _, err := strconv.Atoi(base[1:]); err == nil && path.Dir(p) != "." {
+			base = path.Base(path.Dir(p))
+		}
+	}
+	// e.g. gopkg.in/yaml.v3 and github.com/mattn/go-sqlite3
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); i != -1 {
+		base = base[:i]
+	}
+	return base
+}
@@ -0,0 +1,171 @@
+package conflictresolver
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"testing"
+
+	"github.com/kitimark/dx/pkg/conflictfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeGoImports(t *testing.T) {
+	cf, err := conflictfile.Parse([]byte(`package app
+
+import (
+	"fmt"
+<<<<<<< HEAD
+	"os"
+	"strings"
+||||||| base
+	"os"
+	"sort"
+=======
+	"sort"
+
+	"gopkg.in/yaml.v3"
+>>>>>>> feature
+)
+
+func main() {
+	fmt.Println(os.Args, strings.ToUpper("a"))
+	yaml.Marshal(nil)
+}
+`), conflictfile.DefaultMarkerSize)
+	require.NoError(t, err)
+	require.True(t, isImportOnlyConflict(cf))
+
+	b, err := mergeGoImports(cf)
+	require.NoError(t, err)
+	// sort is removed by ours, os is kept because theirs only dropped the import
+	assert.Equal(t, `package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	fmt.Println(os.Args, strings.ToUpper("a"))
+	yaml.Marshal(nil)
+}
+`, string(b))
+}
+
+func TestMergeGoImports_ShadowedName(t *testing.T) {
+	cf, err := conflictfile.Parse([]byte(`package app
+
+import (
+	"fmt"
+<<<<<<< HEAD
+	"path"
+=======
+	"strings"
+>>>>>>> feature
+)
+
+type route struct{ Join string }
+
+type config struct{ strings []string }
+
+func main() {
+	path := route{Join: "/"}
+	fmt.Println(path.Join)
+	cfg := config{}
+	fmt.Println(cfg.strings, strings.ToUpper("a"))
+}
+`), conflictfile.DefaultMarkerSize)
+	require.NoError(t, err)
+	require.True(t, isImportOnlyConflict(cf))
+
+	b, err := mergeGoImports(cf)
+	require.NoError(t, err)
+	// path.Join and cfg.strings are the fields of local variables, not the packages
+	assert.Equal(t, `package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+type route struct{ Join string }
+
+type config struct{ strings []string }
+
+func main() {
+	path := route{Join: "/"}
+	fmt.Println(path.Join)
+	cfg := config{}
+	fmt.Println(cfg.strings, strings.ToUpper("a"))
+}
+`, string(b))
+}
+
+func TestMergeGoImports_Duplicated(t *testing.T) {
+	cf, err := conflictfile.Parse([]byte(`package app
+
+<<<<<<< HEAD
+import "errors"
+=======
+import (
+	"errors"
+	"log"
+)
+>>>>>>> feature
+
+var err = errors.New("x")
+`), conflictfile.DefaultMarkerSize)
+	require.NoError(t, err)
+	require.True(t, isImportOnlyConflict(cf))
+
+	b, err := mergeGoImports(cf)
+	require.NoError(t, err)
+	assert.Equal(t, `package app
+
+import "errors"
+
+var err = errors.New("x")
+`, string(b))
+}
+
+func TestIsImportOnlyConflict(t *testing.T) {
+	cf, err := conflictfile.Parse([]byte(`package app
+
+import (
+<<<<<<< HEAD
+	"os"
+=======
+	"sort"
+>>>>>>> feature
+)
+
+func main() {
+<<<<<<< HEAD
+	os.Exit(0)
+=======
+	sort.Ints(nil)
+>>>>>>> feature
+}
+`), conflictfile.DefaultMarkerSize)
+	require.NoError(t, err)
+	assert.False(t, isImportOnlyConflict(cf))
+}
+
+func TestImportName(t *testing.T) {
+	for path, name := range map[string]string{
+		"fmt":                          "fmt",
+		"net/http":                     "http",
+		"github.com/go-chi/chi/v5":     "chi",
+		"gopkg.in/yaml.v3":             "yaml",
+		"github.com/mattn/go-sqlite3":  "sqlite3",
+		"github.com/kitimark/dx/pkg/x": "x",
+	} {
+		spec := &ast.ImportSpec{Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(path)}}
+		assert.Equal(t, name, importName(spec), path)
+	}
+}
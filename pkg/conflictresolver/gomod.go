@@ -1,19 +1,24 @@
 package conflictresolver
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"regexp"
 	"slices"
 	"strings"
 
+	"github.com/kitimark/dx/pkg/conflictfile"
 	"github.com/kitimark/dx/pkg/exec"
 	"github.com/kitimark/dx/pkg/utils"
 	"golang.org/x/mod/modfile"
 )
 
-type GoModResolver struct{}
+type GoModResolver struct {
+	offline bool
+}
 
 var goModConflictedFileLists = []string{"go.mod", "go.sum"}
 
@@ -21,45 +26,124 @@ func (r *GoModResolver) Name() string {
 	return "go mod"
 }
 
-func (r *GoModResolver) Detect(fileNames []string) bool {
-	for _, f := range fileNames {
-		if slices.Contains(goModConflictedFileLists, f) {
-			return true
+// SetOffline makes the resolver merge go.sum with the local module cache
+// instead of `go mod tidy`
+func (r *GoModResolver) SetOffline(offline bool) {
+	r.offline = offline
+}
+
+func (r *GoModResolver) Detect(files []ConflictedFile) bool {
+	return len(dirsOf(files, goModConflictedFileLists...)) != 0 ||
+		len(dirsOf(files, goWorkConflictedFileLists...)) != 0 ||
+		len(goVendorRootsOf(files)) != 0
+}
+
+// Resolve resolves go.work, go.mod and go.sum of each workspace and module that they are
+// conflicted, the conflicted go files of the module must be resolved first.
+// the committed vendor directories are regenerated at the end
+func (r *GoModResolver) Resolve(files []ConflictedFile) error {
+	modDirs := dirsOf(files, goModConflictedFileLists...)
+	workDirs := dirsOf(files, goWorkConflictedFileLists...)
+	vendorDirs := goVendorRootsOf(files)
+	for _, dir := range slices.Concat(modDirs, vendorDirs) {
+		err := checkGoFilesConflictedIsResolved(filesOfGoModule(files, dir))
+		if err != nil {
+			return err
 		}
 	}
-	return false
-}
 
-func (r *GoModResolver) Resolve(fileNames []string) error {
-	err := checkGoFilesConflictedIsResolved(fileNames)
-	if err != nil {
-		return err
+	// go command reads go.work of the workspace, it must be resolved before the modules
+	for _, dir := range workDirs {
+		err := resolveGoWorkConflicted(files, dir)
+		if err != nil {
+			return err
+		}
 	}
-	err = resolveGoModConflicted(fileNames)
-	if err != nil {
-		return err
+
+	for _, dir := range modDirs {
+		err := resolveGoModConflicted(filesOfGoModule(files, dir))
+		if err != nil {
+			return err
+		}
+		if r.offline {
+			slog.Info("resolve go.sum with the local module cache", "dir", dir)
+			err = resolveGoSumOffline(dir)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		slog.Info("run go mod tidy", "dir", dir)
+		out, err := exec.OutputErrDir(dir, "go", "mod", "tidy")
+		if err != nil {
+			slog.Error(out)
+			return err
+		}
 	}
-	out, err := exec.OutputErr("go", "mod", "tidy")
-	if err != nil {
-		slog.Error(out)
-		return err
+
+	for _, dir := range workDirs {
+		err := syncGoWork(dir, r.offline)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, dir := range slices.Concat(modDirs, workDirs) {
+		if hasGoVendor(dir) && !slices.Contains(vendorDirs, dir) {
+			vendorDirs = append(vendorDirs, dir)
+		}
+	}
+	slices.Sort(vendorDirs)
+	for _, dir := range vendorDirs {
+		err := vendorGo(dir, r.offline)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// filesOfGoModule returns the conflicted files that owned by the module in dir,
+// the vendored files are regenerated so they're excluded
+func filesOfGoModule(files []ConflictedFile, dir string) []ConflictedFile {
+	var moduleFiles []ConflictedFile
+	for _, f := range files {
+		if _, vendored := goVendorRoot(f.Path); vendored {
+			continue
+		}
+		if slices.Contains(goModConflictedFileLists, filepath.Base(f.Path)) {
+			if filepath.Dir(f.Path) == dir {
+				moduleFiles = append(moduleFiles, f)
+			}
+			continue
+		}
+		owner, ok := findOwnerDir(f.Path, "go.mod")
+		if ok && owner == dir {
+			moduleFiles = append(moduleFiles, f)
+		}
+	}
+	return moduleFiles
+}
+
 var goFileRegex = regexp.MustCompile("(.*)\\.go")
 
-func checkGoFilesConflictedIsResolved(conflictedFiles []string) error {
+func checkGoFilesConflictedIsResolved(conflictedFiles []ConflictedFile) error {
 	var stillConflictedFiles []string
 	for _, f := range conflictedFiles {
-		if goFileRegex.MatchString(f) {
-			isStillConflicted, err := isContentStillConflict(f)
-			if err != nil {
-				return err
-			}
-			if isStillConflicted {
-				stillConflictedFiles = append(stillConflictedFiles, f)
-			}
+		if !goFileRegex.MatchString(f.Path) {
+			continue
+		}
+		// the file is deleted by one side, it must be decided by human
+		if f.Kind.IsDeleted() {
+			stillConflictedFiles = append(stillConflictedFiles, f.Path+" ("+f.Kind.String()+")")
+			continue
+		}
+		isStillConflicted, err := conflictfile.IsConflicted(f.Path)
+		if err != nil {
+			return err
+		}
+		if isStillConflicted {
+			stillConflictedFiles = append(stillConflictedFiles, f.Path)
 		}
 	}
 
@@ -70,36 +154,36 @@ func checkGoFilesConflictedIsResolved(conflictedFiles []string) error {
 	return nil
 }
 
-var conflictContentPattern = regexp.MustCompile("^(<<<<<<<|=======|>>>>>>>)(.*)")
-
-func isContentStillConflict(file string) (bool, error) {
-	content, err := os.ReadFile(file)
-	if err != nil {
-		return false, err
-	}
-	for _, line := range strings.Split(string(content), "\n") {
-		if conflictContentPattern.MatchString(line) {
-			return true, nil
-		}
-	}
-	return false, nil
-}
-
-func resolveGoModConflicted(files []string) error {
-	for _, filename := range files {
-		if !slices.Contains(goModConflictedFileLists, filename) {
+func resolveGoModConflicted(files []ConflictedFile) error {
+	for _, f := range files {
+		filename := f.Path
+		if !slices.Contains(goModConflictedFileLists, filepath.Base(filename)) {
 			continue
 		}
-		b, err := os.ReadFile(filename)
+		if f.Kind.IsDeleted() {
+			// go.sum is regenerated by `go mod tidy` whatever side deleted it
+			if filepath.Base(filename) == "go.sum" {
+				continue
+			}
+			return fmt.Errorf("%s is %s, resolve it first", filename, f.Kind)
+		}
+		// the file might be already resolved, e.g. by a policy rule
+		isStillConflict, err := conflictfile.IsConflicted(filename)
 		if err != nil {
 			return err
 		}
-		b = removeConflictAnnotation(b)
-		if filename == "go.mod" {
-			b, err = formatGoMod(filename, b)
-			if err != nil {
-				return err
-			}
+		if !isStillConflict {
+			slog.Debug("skip resolved file", "file", filename)
+			continue
+		}
+		var b []byte
+		if filepath.Base(filename) == "go.mod" {
+			b, err = resolveGoMod(filename)
+		} else {
+			b, err = unionConflict(filename)
+		}
+		if err != nil {
+			return err
 		}
 		err = os.WriteFile(filename, b, 0)
 		if err != nil {
@@ -109,14 +193,29 @@ func resolveGoModConflicted(files []string) error {
 	return nil
 }
 
-func removeConflictAnnotation(b []byte) []byte {
-	var result []string
-	for _, line := range strings.Split(string(b), "\n") {
-		if !conflictContentPattern.MatchString(line) {
-			result = append(result, line)
-		}
+// resolveGoMod merges go.mod from the index stages, it falls back to keep
+// both sides of the conflict when the stages are gone, e.g. the file is staged
+func resolveGoMod(filename string) ([]byte, error) {
+	b, err := mergeGoModFromIndex(filename)
+	if !errors.Is(err, errNotInIndex) {
+		return b, err
+	}
+	slog.Debug("go.mod is not in the index stages, fall back to keep both sides", "file", filename)
+	b, err = unionConflict(filename)
+	if err != nil {
+		return nil, err
+	}
+	return formatGoMod(filename, b)
+}
+
+// unionConflict keeps both sides of the conflict, the duplicated lines
+// are resolved by formatGoMod and `go mod tidy`
+func unionConflict(filename string) ([]byte, error) {
+	cf, err := conflictfile.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse conflict of %s: %w", filename, err)
 	}
-	return []byte(strings.Join(result, "\n"))
+	return cf.Resolve(conflictfile.Union), nil
 }
 
 var dontFixRetract modfile.VersionFixer = func(_, vers string) (string, error) {
@@ -2,8 +2,11 @@ package conflictresolver
 
 import (
 	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
+	"github.com/kitimark/dx/pkg/conflictfile"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -11,9 +14,10 @@ import (
 func TestIssuePanicWhenGoModConflictWithTheSameMods(t *testing.T) {
 	f, err := os.ReadFile("./fixtures/issue_conflict_same_mods/go.mod")
 	require.NoError(t, err)
-	f = removeConflictAnnotation(f)
+	cf, err := conflictfile.Parse(f, conflictfile.DefaultMarkerSize)
+	require.NoError(t, err)
 
-	actual, err := formatGoMod("go.mod", f)
+	actual, err := formatGoMod("go.mod", cf.Resolve(conflictfile.Union))
 	assert.NoError(t, err)
 	assert.NotNil(t, actual)
 	// expect the conflict annotation is removed, it's not resolve mods yet.
@@ -34,3 +38,19 @@ require (
 )
 `), actual)
 }
+
+func TestResolveGoModConflicted_SkipResolved(t *testing.T) {
+	// go.mod is resolved by a policy rule before the resolver runs
+	gomod := "module example.com/app\n\ngo 1.22\nrequire example.com/lib v1.0.0\nrequire example.com/extra v1.0.0\n"
+	dir := t.TempDir()
+	out, err := exec.Command("git", "init", dir).CombinedOutput()
+	require.NoError(t, err, string(out))
+	path := filepath.Join(dir, "go.mod")
+	require.NoError(t, os.WriteFile(path, []byte(gomod), 0644))
+
+	err = resolveGoModConflicted([]ConflictedFile{{Path: path, Kind: BothModified}})
+	require.NoError(t, err)
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, gomod, string(b))
+}
@@ -0,0 +1,461 @@
+package conflictresolver
+
+import (
+	"fmt"
+	"go/version"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// mergeGoModFromIndex merges go.mod of ours and theirs stages against the base stage.
+// the result is based on ours, so its comments and block layout are kept
+func mergeGoModFromIndex(path string) ([]byte, error) {
+	stages, err := readIndexStages(path)
+	if err != nil {
+		return nil, err
+	}
+	if stages[stageOurs] == nil || stages[stageTheirs] == nil {
+		return nil, fmt.Errorf("%s is deleted by one side, resolve it first", path)
+	}
+	base := &modfile.File{}
+	if stages[stageBase] != nil {
+		base, err = modfile.Parse(path, stages[stageBase], dontFixRetract)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ours, err := modfile.Parse(path, stages[stageOurs], dontFixRetract)
+	if err != nil {
+		return nil, err
+	}
+	theirs, err := modfile.Parse(path, stages[stageTheirs], dontFixRetract)
+	if err != nil {
+		return nil, err
+	}
+	err = mergeGoMod(base, ours, theirs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot merge %s: %w", path, err)
+	}
+	ours.Cleanup()
+	return modfile.Format(ours.Syntax), nil
+}
+
+// mergeGoMod applies the changes of theirs from base into ours. when both sides
+// changed the same module, the semver maximum is picked
+func mergeGoMod(base, ours, theirs *modfile.File) error {
+	m := merge3(modulePaths(base), modulePaths(ours), modulePaths(theirs))
+	path, _, err := m.value("", func(o, t string) (string, error) {
+		return "", fmt.Errorf("module path is changed to %s and %s", o, t)
+	})
+	if err != nil {
+		return err
+	}
+	if path != "" && (ours.Module == nil || ours.Module.Mod.Path != path) {
+		err = ours.AddModuleStmt(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = mergeGoStmt(base.Go, ours.Go, theirs.Go, ours)
+	if err != nil {
+		return err
+	}
+	err = mergeToolchainStmt(base.Toolchain, ours.Toolchain, theirs.Toolchain, ours)
+	if err != nil {
+		return err
+	}
+	mergeRequires(base, ours, theirs)
+	err = mergeReplaces(base.Replace, ours.Replace, theirs.Replace, ours)
+	if err != nil {
+		return err
+	}
+	err = mergeExcludes(base, ours, theirs)
+	if err != nil {
+		return err
+	}
+	return mergeRetracts(base, ours, theirs)
+}
+
+// goDirectives are the directives that go.mod and go.work have in common,
+// the merged directives are written into it
+type goDirectives interface {
+	AddGoStmt(version string) error
+	DropGoStmt()
+	AddToolchainStmt(name string) error
+	DropToolchainStmt()
+	AddReplace(oldPath, oldVers, newPath, newVers string) error
+	DropReplace(oldPath, oldVers string) error
+}
+
+func mergeGoStmt(base, ours, theirs *modfile.Go, dst goDirectives) error {
+	m := merge3(goVersions(base), goVersions(ours), goVersions(theirs))
+	v, ok, err := m.value("", maxGoVersion)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		dst.DropGoStmt()
+		return nil
+	}
+	return dst.AddGoStmt(v)
+}
+
+func mergeToolchainStmt(base, ours, theirs *modfile.Toolchain, dst goDirectives) error {
+	m := merge3(toolchains(base), toolchains(ours), toolchains(theirs))
+	v, ok, err := m.value("", func(o, t string) (string, error) {
+		if version.Compare(o, t) >= 0 {
+			return o, nil
+		}
+		return t, nil
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		dst.DropToolchainStmt()
+		return nil
+	}
+	return dst.AddToolchainStmt(v)
+}
+
+type requireValue struct {
+	version  string
+	indirect bool
+}
+
+func mergeRequires(base, ours, theirs *modfile.File) {
+	values := func(f *modfile.File) map[string]requireValue {
+		vs := map[string]requireValue{}
+		for _, r := range f.Require {
+			vs[r.Mod.Path] = requireValue{version: r.Mod.Version, indirect: r.Indirect}
+		}
+		return vs
+	}
+	m := merge3(values(base), values(ours), values(theirs))
+
+	var reqs []*modfile.Require
+	for _, path := range m.keys(requirePaths(ours), requirePaths(theirs)) {
+		v, ok, _ := m.value(path, func(o, t requireValue) (requireValue, error) {
+			return requireValue{
+				version:  maxVersion(o.version, t.version),
+				indirect: o.indirect && t.indirect,
+			}, nil
+		})
+		if !ok {
+			continue
+		}
+		reqs = append(reqs, &modfile.Require{
+			Mod:      module.Version{Path: path, Version: v.version},
+			Indirect: v.indirect,
+		})
+	}
+	ours.SetRequireSeparateIndirect(reqs)
+}
+
+func mergeReplaces(base, ours, theirs []*modfile.Replace, dst goDirectives) error {
+	values := func(replaces []*modfile.Replace) map[modVersion]modVersion {
+		vs := map[modVersion]modVersion{}
+		for _, r := range replaces {
+			vs[modVersion{r.Old.Path, r.Old.Version}] = modVersion{r.New.Path, r.New.Version}
+		}
+		return vs
+	}
+	m := merge3(values(base), values(ours), values(theirs))
+	for _, key := range m.keys() {
+		v, ok, err := m.value(key, func(o, t modVersion) (modVersion, error) {
+			if o.path != t.path {
+				return o, fmt.Errorf("replace of %s is changed to %s and %s", key.path, o.path, t.path)
+			}
+			return modVersion{o.path, maxVersion(o.version, t.version)}, nil
+		})
+		if err != nil {
+			return err
+		}
+		current, inOurs := m.ours[key]
+		switch {
+		case !ok && inOurs:
+			err = dst.DropReplace(key.path, key.version)
+		case ok && (!inOurs || current != v):
+			err = dst.AddReplace(key.path, key.version, v.path, v.version)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modVersion is comparable module path and version, the version might be empty
+type modVersion struct{ path, version string }
+
+// mergeExcludes edits the excludes of ours in place, so their block layout and comments
+// are kept. the removed exclude is reused for the added one, the rest are added with
+// the comments of theirs
+func mergeExcludes(base, ours, theirs *modfile.File) error {
+	values := func(f *modfile.File) map[modVersion]bool {
+		vs := map[modVersion]bool{}
+		for _, e := range f.Exclude {
+			vs[modVersion{e.Mod.Path, e.Mod.Version}] = true
+		}
+		return vs
+	}
+	m := merge3(values(base), values(ours), values(theirs))
+	var dropped []*modfile.Exclude
+	var added []modVersion
+	for _, key := range m.keys() {
+		_, ok, _ := m.value(key, keepOurs[bool])
+		switch {
+		case !ok && m.ours[key]:
+			for _, e := range ours.Exclude {
+				if e.Mod.Path == key.path && e.Mod.Version == key.version {
+					dropped = append(dropped, e)
+				}
+			}
+		case ok && !m.ours[key]:
+			added = append(added, key)
+		}
+	}
+
+	for i, key := range added {
+		if i < len(dropped) {
+			e := dropped[i]
+			e.Mod = module.Version{Path: key.path, Version: key.version}
+			setLineTokens(e.Syntax, modfile.AutoQuote(key.path), key.version)
+			continue
+		}
+		err := ours.AddExclude(key.path, key.version)
+		if err != nil {
+			return err
+		}
+		for _, e := range theirs.Exclude {
+			if e.Mod.Path == key.path && e.Mod.Version == key.version {
+				ours.Exclude[len(ours.Exclude)-1].Syntax.Comments = e.Syntax.Comments
+			}
+		}
+	}
+	for _, e := range dropped[min(len(added), len(dropped)):] {
+		err := ours.DropExclude(e.Mod.Path, e.Mod.Version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeRetracts edits the retracts of ours in place like mergeExcludes
+func mergeRetracts(base, ours, theirs *modfile.File) error {
+	values := func(f *modfile.File) map[modfile.VersionInterval]string {
+		vs := map[modfile.VersionInterval]string{}
+		for _, r := range f.Retract {
+			vs[r.VersionInterval] = r.Rationale
+		}
+		return vs
+	}
+	m := merge3(values(base), values(ours), values(theirs))
+	var dropped []*modfile.Retract
+	var added []modfile.VersionInterval
+	for _, key := range m.keys() {
+		_, ok, _ := m.value(key, keepOurs[string])
+		_, inOurs := m.ours[key]
+		switch {
+		case !ok && inOurs:
+			for _, r := range ours.Retract {
+				if r.VersionInterval == key {
+					dropped = append(dropped, r)
+				}
+			}
+		case ok && !inOurs:
+			added = append(added, key)
+		}
+	}
+
+	for i, key := range added {
+		tokens := []string{modfile.AutoQuote(key.Low)}
+		if key.Low != key.High {
+			tokens = []string{"[", modfile.AutoQuote(key.Low), ",", modfile.AutoQuote(key.High), "]"}
+		}
+		if i < len(dropped) {
+			r := dropped[i]
+			r.VersionInterval = key
+			setLineTokens(r.Syntax, tokens...)
+			continue
+		}
+		// AddRetract writes the rationale as leading comments, the comments
+		// of theirs are copied to the added line instead
+		err := ours.AddRetract(key, "")
+		if err != nil {
+			return err
+		}
+		line := findLine(ours.Syntax, "retract", tokens)
+		for _, r := range theirs.Retract {
+			if r.VersionInterval == key && line != nil {
+				line.Comments = r.Syntax.Comments
+			}
+		}
+	}
+	for _, r := range dropped[min(len(added), len(dropped)):] {
+		err := ours.DropRetract(r.VersionInterval)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setLineTokens replaces the arguments of the directive line, the verb of the line
+// that is not in block and the comments are kept
+func setLineTokens(line *modfile.Line, tokens ...string) {
+	if !line.InBlock && len(line.Token) != 0 {
+		tokens = append([]string{line.Token[0]}, tokens...)
+	}
+	line.Token = tokens
+}
+
+// findLine returns the last line of verb that its arguments are tokens
+func findLine(syntax *modfile.FileSyntax, verb string, tokens []string) *modfile.Line {
+	var found *modfile.Line
+	for _, stmt := range syntax.Stmt {
+		switch stmt := stmt.(type) {
+		case *modfile.Line:
+			if len(stmt.Token) != 0 && stmt.Token[0] == verb && slices.Equal(stmt.Token[1:], tokens) {
+				found = stmt
+			}
+		case *modfile.LineBlock:
+			if len(stmt.Token) == 0 || stmt.Token[0] != verb {
+				continue
+			}
+			for _, l := range stmt.Line {
+				if slices.Equal(l.Token, tokens) {
+					found = l
+				}
+			}
+		}
+	}
+	return found
+}
+
+// threeWay holds values of the same kind of directive from each side by its key
+type threeWay[K comparable, V comparable] struct {
+	base, ours, theirs map[K]V
+}
+
+func merge3[K comparable, V comparable](base, ours, theirs map[K]V) *threeWay[K, V] {
+	return &threeWay[K, V]{base: base, ours: ours, theirs: theirs}
+}
+
+// keys returns keys of every side, the keys in order are returned first
+func (m *threeWay[K, V]) keys(inOrder ...[]K) []K {
+	var keys []K
+	seen := map[K]bool{}
+	add := func(k K) {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	for _, ks := range inOrder {
+		for _, k := range ks {
+			add(k)
+		}
+	}
+	for _, vs := range []map[K]V{m.ours, m.theirs, m.base} {
+		var rest []K
+		for k := range vs {
+			if !seen[k] {
+				rest = append(rest, k)
+			}
+		}
+		sortKeys(rest)
+		for _, k := range rest {
+			add(k)
+		}
+	}
+	return keys
+}
+
+// value returns the merged value of key, ok is false if the key is removed.
+// the side that changed from base wins, both is called when both sides changed.
+// the change is kept when the other side removed the key
+func (m *threeWay[K, V]) value(key K, both func(o, t V) (V, error)) (v V, ok bool, err error) {
+	b, bOk := m.base[key]
+	o, oOk := m.ours[key]
+	t, tOk := m.theirs[key]
+	switch {
+	case oOk == bOk && o == b:
+		return t, tOk, nil
+	case tOk == bOk && t == b:
+		return o, oOk, nil
+	case !oOk && !tOk:
+		return v, false, nil
+	case oOk && tOk:
+		if o == t {
+			return o, true, nil
+		}
+		v, err = both(o, t)
+		return v, true, err
+	case oOk:
+		return o, true, nil
+	default:
+		return t, true, nil
+	}
+}
+
+func keepOurs[V any](o, _ V) (V, error) {
+	return o, nil
+}
+
+func sortKeys[K comparable](keys []K) {
+	slices.SortFunc(keys, func(a, b K) int {
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	})
+}
+
+func modulePaths(f *modfile.File) map[string]string {
+	if f.Module == nil {
+		return map[string]string{}
+	}
+	return map[string]string{"": f.Module.Mod.Path}
+}
+
+func goVersions(g *modfile.Go) map[string]string {
+	if g == nil {
+		return map[string]string{}
+	}
+	return map[string]string{"": g.Version}
+}
+
+func toolchains(t *modfile.Toolchain) map[string]string {
+	if t == nil {
+		return map[string]string{}
+	}
+	return map[string]string{"": t.Name}
+}
+
+func requirePaths(f *modfile.File) []string {
+	var paths []string
+	for _, r := range f.Require {
+		paths = append(paths, r.Mod.Path)
+	}
+	return paths
+}
+
+// maxVersion returns the semver maximum, pseudo versions are compared as semver
+func maxVersion(a, b string) string {
+	if semver.Compare(a, b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// maxGoVersion returns the maximum of go versions, e.g. 1.21rc1 and 1.21.3
+func maxGoVersion(a, b string) (string, error) {
+	if version.Compare("go"+a, "go"+b) >= 0 {
+		return a, nil
+	}
+	return b, nil
+}
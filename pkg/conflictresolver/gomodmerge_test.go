@@ -0,0 +1,137 @@
+package conflictresolver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
+)
+
+func tparseGoMod(t *testing.T, content string) *modfile.File {
+	t.Helper()
+	f, err := modfile.Parse("go.mod", []byte(content), dontFixRetract)
+	require.NoError(t, err)
+	return f
+}
+
+func TestMergeGoMod(t *testing.T) {
+	base := tparseGoMod(t, `module example.com/app
+
+go 1.21
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.0.0
+	example.com/removed v1.0.0
+)
+
+require example.com/c v1.0.0 // indirect
+
+replace example.com/a => ../a
+
+exclude example.com/x v1.0.0
+
+retract v0.1.0
+`)
+	ours := tparseGoMod(t, `// app is the api service
+module example.com/app
+
+go 1.22
+
+require (
+	example.com/a v1.2.0
+	example.com/b v1.1.0
+	example.com/ours v1.0.0
+)
+
+require example.com/c v1.0.0 // indirect
+
+replace example.com/a => ../a
+
+exclude example.com/x v1.0.0
+
+retract v0.1.0
+`)
+	theirs := tparseGoMod(t, `module example.com/app
+
+go 1.21.5
+
+toolchain go1.22.3
+
+require (
+	example.com/a v1.3.0
+	example.com/b v1.0.0
+	example.com/removed v1.0.0
+	example.com/theirs v0.0.0-20240101000000-abcdefabcdef
+)
+
+require example.com/c v1.1.0 // indirect
+
+replace example.com/a => ../a-fork
+
+exclude example.com/y v1.0.0
+
+retract (
+	v0.1.0
+	v0.2.0 // broken build
+)
+`)
+	err := mergeGoMod(base, ours, theirs)
+	require.NoError(t, err)
+	ours.Cleanup()
+	assert.Equal(t, `// app is the api service
+module example.com/app
+
+go 1.22
+
+toolchain go1.22.3
+
+require (
+	example.com/a v1.3.0
+	example.com/b v1.1.0
+	example.com/ours v1.0.0
+	example.com/theirs v0.0.0-20240101000000-abcdefabcdef
+)
+
+require example.com/c v1.1.0 // indirect
+
+replace example.com/a => ../a-fork
+
+exclude example.com/y v1.0.0
+
+retract (
+	v0.1.0
+	v0.2.0 // broken build
+)
+`, string(modfile.Format(ours.Syntax)))
+	assert.Equal(t, "example.com/y", ours.Exclude[0].Mod.Path)
+}
+
+func TestMergeGoMod_ReplaceConflict(t *testing.T) {
+	base := tparseGoMod(t, "module example.com/app\n\ngo 1.21\n")
+	ours := tparseGoMod(t, "module example.com/app\n\ngo 1.21\n\nreplace example.com/a => ../a\n")
+	theirs := tparseGoMod(t, "module example.com/app\n\ngo 1.21\n\nreplace example.com/a => example.com/fork v1.0.0\n")
+	err := mergeGoMod(base, ours, theirs)
+	assert.ErrorContains(t, err, "replace of example.com/a is changed to ../a and example.com/fork")
+}
+
+func TestMergeGoMod_BothAdded(t *testing.T) {
+	ours := tparseGoMod(t, "module example.com/app\n\ngo 1.21\n\nrequire example.com/a v1.1.0\n")
+	theirs := tparseGoMod(t, "module example.com/app\n\ngo 1.22\n\nrequire example.com/a v1.0.0\n")
+	err := mergeGoMod(&modfile.File{}, ours, theirs)
+	require.NoError(t, err)
+	ours.Cleanup()
+	assert.Equal(t, "module example.com/app\n\ngo 1.22\n\nrequire example.com/a v1.1.0\n", string(modfile.Format(ours.Syntax)))
+}
+
+func TestMergeGoMod_ExcludeInPlace(t *testing.T) {
+	base := tparseGoMod(t, "module example.com/app\n\nexclude (\n\texample.com/x v1.0.0\n\texample.com/y v1.0.0\n)\n\nrequire example.com/a v1.0.0\n")
+	ours := tparseGoMod(t, "module example.com/app\n\nexclude (\n\texample.com/x v1.0.0 // flaky\n\texample.com/y v1.0.0\n)\n\nrequire example.com/a v1.0.0\n")
+	theirs := tparseGoMod(t, "module example.com/app\n\nexclude (\n\texample.com/x v1.1.0\n\texample.com/y v1.0.0\n)\n\nrequire example.com/a v1.0.0\n")
+	err := mergeGoMod(base, ours, theirs)
+	require.NoError(t, err)
+	ours.Cleanup()
+	assert.Equal(t, "module example.com/app\n\nexclude (\n\texample.com/x v1.1.0 // flaky\n\texample.com/y v1.0.0\n)\n\nrequire example.com/a v1.0.0\n",
+		string(modfile.Format(ours.Syntax)))
+}
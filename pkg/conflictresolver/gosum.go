@@ -0,0 +1,257 @@
+package conflictresolver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/exec"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// goSumLine is a line of go.sum, version has `/go.mod` suffix
+// when the hash is of go.mod file
+//
+// ### Example go.sum
+//
+//	github.com/fatih/color v1.18.0 h1:S8gINlzdQ840/4pfAwic/ZE0djQEH3wM94VfqLTZcOM=
+//	github.com/fatih/color v1.18.0/go.mod h1:4FelSpRwEGDpQ12mAdzqdOukCy4u8WUtOY6lkT/6HfU=
+type goSumLine struct {
+	path    string
+	version string
+	hash    string
+}
+
+func (l goSumLine) isGoMod() bool {
+	return strings.HasSuffix(l.version, "/go.mod")
+}
+
+func (l goSumLine) moduleVersion() string {
+	return strings.TrimSuffix(l.version, "/go.mod")
+}
+
+func (l goSumLine) String() string {
+	return l.path + " " + l.version + " " + l.hash
+}
+
+func parseGoSum(b []byte) []goSumLine {
+	var lines []goSumLine
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		l := goSumLine{path: fields[0], version: fields[1], hash: fields[2]}
+		if !slices.Contains(lines, l) {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+func formatGoSum(lines []goSumLine) []byte {
+	slices.SortFunc(lines, func(a, b goSumLine) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	var sb strings.Builder
+	for _, l := range lines {
+		sb.WriteString(l.String() + "\n")
+	}
+	return []byte(sb.String())
+}
+
+// offlineGoEnv makes go command use only the local module cache
+func offlineGoEnv() []string {
+	return []string{
+		"GOFLAGS=" + strings.TrimSpace(os.Getenv("GOFLAGS")+" -mod=mod"),
+		"GOPROXY=off",
+	}
+}
+
+// resolveGoSumOffline rewrites go.sum of module in dir, it must be the union of both sides.
+// the entries of modules that are no longer required are removed, and the rest
+// are verified against the local module cache
+func resolveGoSumOffline(dir string) error {
+	sumFile := filepath.Join(dir, "go.sum")
+	b, err := os.ReadFile(sumFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	lines := parseGoSum(b)
+	// write the union first, so loading module graph doesn't look for missing entries
+	err = os.WriteFile(sumFile, formatGoSum(lines), 0644)
+	if err != nil {
+		return err
+	}
+
+	graph, err := loadModuleGraphOffline(dir)
+	if err != nil {
+		slog.Warn("cannot load module graph from the local module cache, only stale versions of required modules are removed from go.sum",
+			"dir", dir, "error", err)
+		graph, err = readDirectRequires(dir)
+		if err != nil {
+			return err
+		}
+	}
+	lines = slices.DeleteFunc(lines, func(l goSumLine) bool {
+		return !isGoSumLineRequired(l, graph)
+	})
+
+	unverified, err := verifyGoSumOffline(dir, lines)
+	if err != nil {
+		return err
+	}
+	if len(unverified) != 0 {
+		slog.Warn(fmt.Sprintf("cannot verify go.sum of %d modules with the local module cache, "+
+			"run \"go mod verify\" when network is available\n%s", len(unverified), strings.Join(unverified, "\n")),
+			"dir", dir)
+	}
+	return os.WriteFile(sumFile, formatGoSum(lines), 0644)
+}
+
+// moduleGraph is map of module path to its selected version, the version is empty
+// when only the module path is known to be required
+type moduleGraph map[string]string
+
+func isGoSumLineRequired(l goSumLine, graph moduleGraph) bool {
+	selected, ok := graph[l.path]
+	if !ok {
+		return false
+	}
+	// go.mod of the other versions might be needed to build module graph
+	return l.isGoMod() || selected == "" || selected == l.moduleVersion()
+}
+
+type goListModule struct {
+	Path    string
+	Version string
+	Main    bool
+	Replace *goListModule
+}
+
+// loadModuleGraphOffline returns every module in the build list of module in dir
+func loadModuleGraphOffline(dir string) (moduleGraph, error) {
+	out, err := exec.OutputErrDirEnv(dir, offlineGoEnv(), "go", "list", "-m", "-json", "all")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", out, err)
+	}
+	graph := moduleGraph{}
+	dec := json.NewDecoder(strings.NewReader(out))
+	for {
+		var m goListModule
+		err = dec.Decode(&m)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid output of go list: %w", err)
+		}
+		if m.Main {
+			continue
+		}
+		graph[m.Path] = m.Version
+		if m.Replace != nil && m.Replace.Version != "" {
+			graph[m.Replace.Path] = m.Replace.Version
+		}
+	}
+	return graph, nil
+}
+
+// readDirectRequires returns the modules that required by go.mod in dir, the other
+// module paths are kept in go.sum with any version because they might be indirect
+func readDirectRequires(dir string) (moduleGraph, error) {
+	sumFile := filepath.Join(dir, "go.sum")
+	modFile := filepath.Join(dir, "go.mod")
+	b, err := os.ReadFile(modFile)
+	if err != nil {
+		return nil, err
+	}
+	gomod, err := modfile.Parse(modFile, b, dontFixRetract)
+	if err != nil {
+		return nil, err
+	}
+	b, err = os.ReadFile(sumFile)
+	if err != nil {
+		return nil, err
+	}
+	graph := moduleGraph{}
+	for _, l := range parseGoSum(b) {
+		graph[l.path] = ""
+	}
+	for _, r := range gomod.Require {
+		graph[r.Mod.Path] = r.Mod.Version
+	}
+	for _, r := range gomod.Replace {
+		if r.New.Version != "" {
+			graph[r.New.Path] = r.New.Version
+		}
+	}
+	return graph, nil
+}
+
+// verifyGoSumOffline compares go.sum entries with the hashes of the local module cache,
+// it returns the modules that are not in the cache. hash mismatch is an error
+func verifyGoSumOffline(dir string, lines []goSumLine) (unverified []string, err error) {
+	out, err := exec.OutputErrDirEnv(dir, offlineGoEnv(), "go", "env", "GOMODCACHE")
+	if err != nil {
+		return nil, fmt.Errorf("cannot get GOMODCACHE: %s: %w", out, err)
+	}
+	modCache := strings.TrimSpace(out)
+
+	for _, l := range lines {
+		mv := l.path + "@" + l.moduleVersion()
+		expected, err := cachedGoSumHash(modCache, l)
+		if os.IsNotExist(err) {
+			if !slices.Contains(unverified, mv) {
+				unverified = append(unverified, mv)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if expected != l.hash {
+			return nil, fmt.Errorf("go.sum of %s %s doesn't match the local module cache: %s != %s",
+				l.path, l.version, l.hash, expected)
+		}
+	}
+	return unverified, nil
+}
+
+// cachedGoSumHash returns hash of the go.sum line from the download cache,
+// it's the same hash that go command records into go.sum
+//
+// ### Example files of the download cache
+//
+//	$GOMODCACHE/cache/download/github.com/fatih/color/@v/v1.18.0.mod
+//	$GOMODCACHE/cache/download/github.com/fatih/color/@v/v1.18.0.ziphash
+func cachedGoSumHash(modCache string, l goSumLine) (string, error) {
+	path, err := module.EscapePath(l.path)
+	if err != nil {
+		return "", err
+	}
+	version, err := module.EscapeVersion(l.moduleVersion())
+	if err != nil {
+		return "", err
+	}
+	prefix := filepath.Join(modCache, "cache", "download", path, "@v", version)
+	if !l.isGoMod() {
+		b, err := os.ReadFile(prefix + ".ziphash")
+		return strings.TrimSpace(string(b)), err
+	}
+	_, err = os.Stat(prefix + ".mod")
+	if err != nil {
+		return "", err
+	}
+	return dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return os.Open(prefix + ".mod")
+	})
+}
@@ -0,0 +1,64 @@
+package conflictresolver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGoSum(t *testing.T) {
+	lines := parseGoSum([]byte(`example.com/b v1.0.0 h1:b=
+example.com/a v1.0.0/go.mod h1:amod=
+example.com/b v1.0.0 h1:b=
+
+example.com/a v1.0.0 h1:a=
+`))
+	require.Len(t, lines, 3)
+	assert.True(t, lines[1].isGoMod())
+	assert.Equal(t, "v1.0.0", lines[1].moduleVersion())
+	assert.Equal(t, `example.com/a v1.0.0 h1:a=
+example.com/a v1.0.0/go.mod h1:amod=
+example.com/b v1.0.0 h1:b=
+`, string(formatGoSum(lines)))
+}
+
+func TestIsGoSumLineRequired(t *testing.T) {
+	graph := moduleGraph{"example.com/a": "v1.1.0", "example.com/indirect": ""}
+	assert.True(t, isGoSumLineRequired(goSumLine{path: "example.com/a", version: "v1.1.0"}, graph))
+	assert.True(t, isGoSumLineRequired(goSumLine{path: "example.com/a", version: "v1.0.0/go.mod"}, graph))
+	assert.False(t, isGoSumLineRequired(goSumLine{path: "example.com/a", version: "v1.0.0"}, graph))
+	assert.True(t, isGoSumLineRequired(goSumLine{path: "example.com/indirect", version: "v0.1.0"}, graph))
+	assert.False(t, isGoSumLineRequired(goSumLine{path: "example.com/removed", version: "v1.0.0/go.mod"}, graph))
+}
+
+func TestVerifyGoSumOffline(t *testing.T) {
+	modCache := t.TempDir()
+	t.Setenv("GOMODCACHE", modCache)
+	downloadDir := filepath.Join(modCache, "cache", "download", "example.com", "!upper", "@v")
+	require.NoError(t, os.MkdirAll(downloadDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(downloadDir, "v1.0.0.mod"), []byte("module example.com/Upper\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(downloadDir, "v1.0.0.ziphash"), []byte("h1:zip=\n"), 0644))
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module test\n"), 0644))
+
+	gomodHash, err := cachedGoSumHash(modCache, goSumLine{path: "example.com/Upper", version: "v1.0.0/go.mod"})
+	require.NoError(t, err)
+
+	unverified, err := verifyGoSumOffline(dir, []goSumLine{
+		{path: "example.com/Upper", version: "v1.0.0", hash: "h1:zip="},
+		{path: "example.com/Upper", version: "v1.0.0/go.mod", hash: gomodHash},
+		{path: "example.com/missing", version: "v1.0.0", hash: "h1:missing="},
+		{path: "example.com/missing", version: "v1.0.0/go.mod", hash: "h1:missing="},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com/missing@v1.0.0"}, unverified)
+
+	_, err = verifyGoSumOffline(dir, []goSumLine{
+		{path: "example.com/Upper", version: "v1.0.0", hash: "h1:tampered="},
+	})
+	assert.ErrorContains(t, err, "doesn't match the local module cache")
+}
@@ -0,0 +1,93 @@
+package conflictresolver
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/exec"
+)
+
+const goVendorDirName = "vendor"
+
+// goCommand runs go command in dir, it only uses the local module cache when offline
+func goCommand(dir string, offline bool, args ...string) (string, error) {
+	if offline && args[0] == "work" {
+		// workspace mode rejects -mod=mod, the proxy is enough to stay offline
+		return exec.OutputErrDirEnv(dir, []string{"GOPROXY=off"}, "go", args...)
+	}
+	if offline {
+		return exec.OutputErrDirEnv(dir, offlineGoEnv(), "go", args...)
+	}
+	return exec.OutputErrDir(dir, "go", args...)
+}
+
+// goVendorRoot returns the directory that contains vendor directory of path,
+// ok is false if path is not vendored by a go module or workspace
+func goVendorRoot(path string) (string, bool) {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i := len(parts) - 2; i >= 0; i-- {
+		if parts[i] != goVendorDirName {
+			continue
+		}
+		dir := filepath.FromSlash(strings.Join(parts[:i], "/"))
+		if dir == "" {
+			dir = "."
+		}
+		if isGoModuleOrWorkspace(dir) {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+func isGoModuleOrWorkspace(dir string) bool {
+	for _, name := range []string{"go.mod", "go.work"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// goVendorRootsOf returns sorted unique directories that its vendor directory is conflicted
+func goVendorRootsOf(files []ConflictedFile) []string {
+	var dirs []string
+	for _, f := range files {
+		dir, ok := goVendorRoot(f.Path)
+		if ok && !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	slices.Sort(dirs)
+	return dirs
+}
+
+// hasGoVendor returns true if the module or workspace in dir commits vendor directory
+func hasGoVendor(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, goVendorDirName, "modules.txt"))
+	return err == nil
+}
+
+// vendorGo regenerates vendor directory in dir, then stages its additions and deletions.
+// the workspace vendor is used when dir has go.work
+func vendorGo(dir string, offline bool) error {
+	args := []string{"mod", "vendor"}
+	if _, err := os.Stat(filepath.Join(dir, "go.work")); err == nil {
+		args = []string{"work", "vendor"}
+	}
+	slog.Info("run go "+strings.Join(args, " "), "dir", dir)
+	out, err := goCommand(dir, offline, args...)
+	if err != nil {
+		slog.Error(out)
+		return err
+	}
+	out, err = exec.OutputErrDir(dir, "git", "add", "-A", "--", goVendorDirName)
+	if err != nil {
+		slog.Error(out)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package conflictresolver
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/mod/modfile"
+)
+
+var goWorkConflictedFileLists = []string{"go.work", "go.work.sum"}
+
+// resolveGoWorkConflicted resolves go.work and go.work.sum of the workspace in dir,
+// go.work is merged from the index stages and go.work.sum keeps both sides
+func resolveGoWorkConflicted(files []ConflictedFile, dir string) error {
+	for _, f := range files {
+		if filepath.Dir(f.Path) != dir {
+			continue
+		}
+		switch filepath.Base(f.Path) {
+		case "go.work":
+			if f.Kind.IsDeleted() {
+				return fmt.Errorf("%s is %s, resolve it first", f.Path, f.Kind)
+			}
+			b, err := mergeGoWorkFromIndex(f.Path)
+			if err != nil {
+				return err
+			}
+			err = os.WriteFile(f.Path, b, 0644)
+			if err != nil {
+				return err
+			}
+		case "go.work.sum":
+			// go.work.sum is regenerated by `go work sync` whatever side deleted it
+			if f.Kind.IsDeleted() {
+				continue
+			}
+			b, err := unionConflict(f.Path)
+			if err != nil {
+				return err
+			}
+			err = os.WriteFile(f.Path, formatGoSum(parseGoSum(b)), 0644)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeGoWorkFromIndex merges use, replace, go and toolchain directives
+// of go.work from the index stages, the result is based on ours
+func mergeGoWorkFromIndex(path string) ([]byte, error) {
+	stages, err := readIndexStages(path)
+	if errors.Is(err, errNotInIndex) {
+		return nil, fmt.Errorf("%s is not in the index stages, resolve it manually", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stages[stageOurs] == nil || stages[stageTheirs] == nil {
+		return nil, fmt.Errorf("%s is deleted by one side, resolve it first", path)
+	}
+	base := &modfile.WorkFile{}
+	if stages[stageBase] != nil {
+		base, err = modfile.ParseWork(path, stages[stageBase], dontFixRetract)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ours, err := modfile.ParseWork(path, stages[stageOurs], dontFixRetract)
+	if err != nil {
+		return nil, err
+	}
+	theirs, err := modfile.ParseWork(path, stages[stageTheirs], dontFixRetract)
+	if err != nil {
+		return nil, err
+	}
+	err = mergeGoWork(base, ours, theirs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot merge %s: %w", path, err)
+	}
+	ours.Cleanup()
+	return modfile.Format(ours.Syntax), nil
+}
+
+func mergeGoWork(base, ours, theirs *modfile.WorkFile) error {
+	err := mergeGoStmt(base.Go, ours.Go, theirs.Go, ours)
+	if err != nil {
+		return err
+	}
+	err = mergeToolchainStmt(base.Toolchain, ours.Toolchain, theirs.Toolchain, ours)
+	if err != nil {
+		return err
+	}
+
+	uses := func(f *modfile.WorkFile) map[string]string {
+		vs := map[string]string{}
+		for _, u := range f.Use {
+			vs[u.Path] = u.ModulePath
+		}
+		return vs
+	}
+	m := merge3(uses(base), uses(ours), uses(theirs))
+	for _, path := range m.keys() {
+		modulePath, ok, _ := m.value(path, keepOurs[string])
+		_, inOurs := m.ours[path]
+		switch {
+		case !ok && inOurs:
+			err = ours.DropUse(path)
+		case ok && !inOurs:
+			err = ours.AddUse(path, modulePath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return mergeReplaces(base.Replace, ours.Replace, theirs.Replace, ours)
+}
+
+// syncGoWork updates the modules of the workspace in dir to the build list of the workspace
+func syncGoWork(dir string, offline bool) error {
+	slog.Info("run go work sync", "dir", dir)
+	out, err := goCommand(dir, offline, "work", "sync")
+	if err != nil {
+		slog.Error(out)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package conflictresolver
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/exec"
+)
+
+// MigrationResolver renumbers the incoming SQL migrations that have the same or lower
+// version than the migrations of the base, git merges them without conflict but they break deploys
+type MigrationResolver struct {
+	// Dirs are the migration directories, relative to the repository root
+	Dirs []string
+	// BaseRef has the existing migrations, the migrations that are not in it are incoming
+	BaseRef string
+}
+
+func (r *MigrationResolver) Name() string {
+	return "migration"
+}
+
+// Detect checks the migration directories, the colliding migrations are not conflicted files
+func (r *MigrationResolver) Detect(_ []ConflictedFile) bool {
+	for _, dir := range r.Dirs {
+		migrations, _, err := incomingMigrations(".", dir, r.BaseRef)
+		if err != nil {
+			slog.Debug("cannot list migrations", "dir", dir, "error", err)
+			continue
+		}
+		if len(migrations) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MigrationResolver) Resolve(_ []ConflictedFile) error {
+	_, err := RenumberMigrations(".", r.Dirs, r.BaseRef)
+	return err
+}
+
+// migrationFileRegex matches `<version>_<name>.up.sql`, `<version>_<name>.down.sql`
+// and `<version>_<name>.sql`
+var migrationFileRegex = regexp.MustCompile(`^(\d+)_(.+?)((?:\.up|\.down)?\.sql)$`)
+
+type migration struct {
+	version uint64
+	// width is the number of digits of version, the zero padding is kept
+	width int
+	name  string
+	files []string
+}
+
+// RenumberMigrations renames the migrations in dirs under root that are not in baseRef
+// and have the same or lower version than the highest version of baseRef, they're
+// renumbered after it in their version order with `git mv`. it returns the number of renamed files
+func RenumberMigrations(root string, dirs []string, baseRef string) (int, error) {
+	renamed := 0
+	for _, dir := range dirs {
+		migrations, highest, err := incomingMigrations(root, dir, baseRef)
+		if err != nil {
+			return renamed, err
+		}
+		for _, m := range migrations {
+			if m.version > highest {
+				highest = m.version
+				continue
+			}
+			highest++
+			version := fmt.Sprintf("%0*d", m.width, highest)
+			slog.Info("renumber migration", "dir", dir, "name", m.name, "from", m.version, "to", version)
+			for _, f := range m.files {
+				match := migrationFileRegex.FindStringSubmatch(f)
+				newName := version + "_" + match[2] + match[3]
+				out, err := exec.OutputErrDir(filepath.Join(root, dir), "git", "mv", "--", f, newName)
+				if err != nil {
+					return renamed, fmt.Errorf("cannot rename migration %s: %s: %w", f, out, err)
+				}
+				renamed++
+			}
+		}
+	}
+	return renamed, nil
+}
+
+// incomingMigrations returns the migrations in dir that are not in baseRef sorted by version,
+// with the highest version of baseRef. it returns no migrations when none of them
+// collides with or is older than the highest version
+func incomingMigrations(root, dir, baseRef string) (migrations []*migration, highest uint64, err error) {
+	_, err = os.Stat(filepath.Join(root, dir))
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	files, err := listMigrationFiles(root, dir, "")
+	if err != nil {
+		return nil, 0, err
+	}
+	baseFiles, err := listMigrationFiles(root, dir, baseRef)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, f := range baseFiles {
+		if m, ok := parseMigrationFile(f); ok {
+			highest = max(highest, m.version)
+		}
+	}
+
+	for _, f := range files {
+		if slices.Contains(baseFiles, f) {
+			continue
+		}
+		m, ok := parseMigrationFile(f)
+		if !ok {
+			continue
+		}
+		i := slices.IndexFunc(migrations, func(o *migration) bool {
+			return o.version == m.version && o.name == m.name
+		})
+		if i == -1 {
+			migrations = append(migrations, m)
+			continue
+		}
+		migrations[i].files = append(migrations[i].files, m.files...)
+	}
+	slices.SortFunc(migrations, func(a, b *migration) int {
+		return cmp.Or(cmp.Compare(a.version, b.version), strings.Compare(a.name, b.name))
+	})
+
+	// the migrations after highest are kept unless they collide with each other
+	last := highest
+	for _, m := range migrations {
+		if m.version <= last {
+			return migrations, highest, nil
+		}
+		last = m.version
+	}
+	return nil, highest, nil
+}
+
+// listMigrationFiles returns names of files directly in dir of ref, or of the index
+// when ref is empty. untracked files are not listed, so they're never renamed
+//
+// ### Example output of `git ls-tree --name-only -z HEAD ./`
+//
+//	000001_create_users.down.sql<NUL>000001_create_users.up.sql<NUL>
+func listMigrationFiles(root, dir, ref string) ([]string, error) {
+	args := []string{"ls-tree", "--name-only", "-z", ref, "./"}
+	if ref == "" {
+		args = []string{"ls-files", "-z", "./"}
+	}
+	out, err := exec.OutputErrDir(filepath.Join(root, dir), "git", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error during list migrations of %s: %s: %w", dir, out, err)
+	}
+	var files []string
+	for _, f := range strings.Split(out, "\x00") {
+		// unmerged file is listed once per stage, and files in subdirectories are not migrations
+		if f != "" && !strings.Contains(f, "/") && !slices.Contains(files, f) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+func parseMigrationFile(name string) (*migration, bool) {
+	match := migrationFileRegex.FindStringSubmatch(name)
+	if match == nil {
+		return nil, false
+	}
+	version, err := strconv.ParseUint(match[1], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &migration{
+		version: version,
+		width:   len(match[1]),
+		name:    match[2],
+		files:   []string{name},
+	}, true
+}
@@ -0,0 +1,23 @@
+package conflictresolver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMigrationFile(t *testing.T) {
+	m, ok := parseMigrationFile("000123_add_users.up.sql")
+	assert.True(t, ok)
+	assert.Equal(t, &migration{version: 123, width: 6, name: "add_users", files: []string{"000123_add_users.up.sql"}}, m)
+
+	m, ok = parseMigrationFile("20240101000000_init.sql")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(20240101000000), m.version)
+	assert.Equal(t, "init", m.name)
+
+	for _, name := range []string{"README.md", "add_users.up.sql", "000123.up.sql", "seed/000001_a.sql"} {
+		_, ok = parseMigrationFile(name)
+		assert.False(t, ok, name)
+	}
+}
@@ -0,0 +1,49 @@
+package conflictresolver
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// findOwnerDir returns the nearest directory of path that contains any of
+// markerFiles, e.g. go.mod of the module that owns the path
+func findOwnerDir(path string, markerFiles ...string) (string, bool) {
+	dir := filepath.Dir(path)
+	for {
+		for _, m := range markerFiles {
+			_, err := os.Stat(filepath.Join(dir, m))
+			if err == nil {
+				return dir, true
+			}
+		}
+		if dir == "." || dir == string(filepath.Separator) {
+			return "", false
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// dirsOf returns sorted unique directories of conflicted files
+// that its base name is one of fileNames
+func dirsOf(files []ConflictedFile, fileNames ...string) []string {
+	var dirs []string
+	for _, f := range files {
+		if !slices.Contains(fileNames, filepath.Base(f.Path)) {
+			continue
+		}
+		dir := filepath.Dir(f.Path)
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	slices.Sort(dirs)
+	return dirs
+}
+
+// isUnderDir returns true if path is inside dir, both are relative to the repository root
+func isUnderDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,26 @@
+package conflictresolver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirsOf(t *testing.T) {
+	files := []ConflictedFile{
+		{Path: "services/api/go.sum", Kind: BothModified},
+		{Path: "go.mod", Kind: BothModified},
+		{Path: "services/api/go.mod", Kind: BothModified},
+		{Path: "services/api/main.go", Kind: BothModified},
+	}
+	assert.Equal(t, []string{".", "services/api"}, dirsOf(files, "go.mod", "go.sum"))
+	assert.Empty(t, dirsOf(files, "yarn.lock"))
+}
+
+func TestIsUnderDir(t *testing.T) {
+	assert.True(t, isUnderDir("package.json", "."))
+	assert.True(t, isUnderDir("packages/web/package.json", "."))
+	assert.True(t, isUnderDir("packages/web/package.json", "packages"))
+	assert.False(t, isUnderDir("packages/web/package.json", "apps"))
+	assert.False(t, isUnderDir("..web/package.json", "web"))
+}
@@ -0,0 +1,88 @@
+package conflictresolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/kitimark/dx/pkg/exec"
+)
+
+type PackageLockResolver struct{}
+
+var (
+	packageLockFileName       = "package-lock.json"
+	npmShrinkwrapFileName     = "npm-shrinkwrap.json"
+	packageLockFileNames      = []string{packageLockFileName, npmShrinkwrapFileName}
+	supportedLockfileVersions = []int{1, 2, 3}
+)
+
+func (r *PackageLockResolver) Name() string {
+	return "npm package lock"
+}
+
+// NeedsNetwork is true, npm resolves the versions from the registry
+func (r *PackageLockResolver) NeedsNetwork() bool {
+	return true
+}
+
+func (r *PackageLockResolver) Detect(files []ConflictedFile) bool {
+	return len(dirsOf(files, packageLockFileNames...)) != 0
+}
+
+// Resolve regenerates package-lock.json or npm-shrinkwrap.json in each directory that
+// it's conflicted, package.json must be resolved first. the lockfile version of ours is kept
+func (r *PackageLockResolver) Resolve(files []ConflictedFile) error {
+	for _, dir := range dirsOf(files, packageLockFileNames...) {
+		err := checkPackageJsonConflictedIsResolved(files, dir)
+		if err != nil {
+			return err
+		}
+
+		var lockfileVersion int
+		for _, f := range files {
+			if filepath.Dir(f.Path) != dir || !slices.Contains(packageLockFileNames, filepath.Base(f.Path)) {
+				continue
+			}
+			if f.Kind.IsDeleted() {
+				return fmt.Errorf("%s is %s, resolve it first", f.Path, f.Kind)
+			}
+			lockfileVersion, err = restorePackageLockOurs(f.Path)
+			if err != nil {
+				return err
+			}
+		}
+
+		slog.Info("regenerate npm package lock", "dir", dir, "lockfileVersion", lockfileVersion)
+		out, err := exec.OutputErrDir(dir, "npm", "install", "--package-lock-only", "--ignore-scripts",
+			fmt.Sprintf("--lockfile-version=%d", lockfileVersion))
+		if err != nil {
+			slog.Error(out)
+			return err
+		}
+	}
+	return nil
+}
+
+// restorePackageLockOurs replaces the conflicted lockfile with ours side, so npm
+// starts from a valid lockfile. it returns lockfileVersion of ours
+func restorePackageLockOurs(path string) (int, error) {
+	b, err := readOurs(path)
+	if err != nil {
+		return 0, err
+	}
+	var lock struct {
+		LockfileVersion int `json:"lockfileVersion"`
+	}
+	err = json.Unmarshal(b, &lock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	if !slices.Contains(supportedLockfileVersions, lock.LockfileVersion) {
+		return 0, fmt.Errorf("lockfileVersion %d of %s is not supported", lock.LockfileVersion, path)
+	}
+	return lock.LockfileVersion, os.WriteFile(path, b, 0644)
+}
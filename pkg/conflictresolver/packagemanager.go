@@ -0,0 +1,127 @@
+package conflictresolver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/exec"
+)
+
+// packageManager is the package manager that a node project is managed by
+type packageManager struct {
+	name string
+	// major is the major version, 0 when it's unknown
+	major int
+	// berry is true for Yarn 2 and later
+	berry bool
+	// source is where the package manager is detected from
+	source string
+}
+
+func (pm packageManager) String() string {
+	switch {
+	case pm.major != 0:
+		return fmt.Sprintf("%s %d", pm.name, pm.major)
+	case pm.berry:
+		return pm.name + " 2+"
+	default:
+		return pm.name
+	}
+}
+
+var (
+	yarnrcFileName  = ".yarnrc.yml"
+	yarnPathPattern = regexp.MustCompile(`(?m)^yarnPath:.*yarn-(\d+)\.[^/]*$`)
+)
+
+// detectYarn detects the yarn version that the project in dir needs, in order of
+//  1. `packageManager` field of package.json, e.g. "yarn@4.1.0"
+//  2. .yarnrc.yml, it's only used by Yarn 2 and later
+//  3. header of ours yarn.lock, "# yarn lockfile v1" is Yarn 1 and "__metadata:" is Yarn 2 and later
+func detectYarn(dir string) (packageManager, error) {
+	packageJsonFile := filepath.Join(dir, packageJsonFileName)
+	b, err := os.ReadFile(packageJsonFile)
+	if err != nil && !os.IsNotExist(err) {
+		return packageManager{}, err
+	}
+	if err == nil {
+		var pkg struct {
+			PackageManager string `json:"packageManager"`
+		}
+		err = json.Unmarshal(b, &pkg)
+		if err != nil {
+			return packageManager{}, fmt.Errorf("invalid %s: %w", packageJsonFile, err)
+		}
+		if pkg.PackageManager != "" {
+			name, version, _ := strings.Cut(pkg.PackageManager, "@")
+			major := parseMajor(version)
+			return packageManager{
+				name:   name,
+				major:  major,
+				berry:  name == "yarn" && major >= 2,
+				source: "packageManager of " + packageJsonFile,
+			}, nil
+		}
+	}
+
+	yarnrcFile := filepath.Join(dir, yarnrcFileName)
+	b, err = os.ReadFile(yarnrcFile)
+	if err != nil && !os.IsNotExist(err) {
+		return packageManager{}, err
+	}
+	if err == nil {
+		pm := packageManager{name: "yarn", berry: true, source: yarnrcFile}
+		if m := yarnPathPattern.FindSubmatch(b); m != nil {
+			pm.major, _ = strconv.Atoi(string(m[1]))
+		}
+		return pm, nil
+	}
+
+	lockFile := filepath.Join(dir, yarnLockFileName)
+	b, err = readOurs(lockFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return packageManager{}, err
+	}
+	if strings.Contains(string(b), "\n__metadata:") || strings.HasPrefix(string(b), "__metadata:") {
+		return packageManager{name: "yarn", berry: true, source: "header of " + lockFile}, nil
+	}
+	return packageManager{name: "yarn", major: 1, source: "header of " + lockFile}, nil
+}
+
+// installedYarn returns the yarn that runs in dir, it's the version that
+// pinned by the project when corepack is enabled
+func installedYarn(dir string) (packageManager, error) {
+	out, err := exec.OutputErrDir(dir, "yarn", "--version")
+	if err != nil {
+		return packageManager{}, fmt.Errorf("cannot get yarn version: %s: %w", out, err)
+	}
+	version := strings.TrimSpace(out)
+	major := parseMajor(version)
+	return packageManager{name: "yarn", major: major, berry: major >= 2, source: version}, nil
+}
+
+// checkYarn returns error if installed yarn cannot update the lockfile of expected yarn
+func checkYarn(dir string, expected, installed packageManager) error {
+	if expected.name != "yarn" {
+		return fmt.Errorf("%s is managed by %s (from %s), but yarn.lock is conflicted", dir, expected, expected.source)
+	}
+	if expected.berry != installed.berry || (expected.major != 0 && expected.major != installed.major) {
+		return fmt.Errorf("%s needs %s (from %s), but yarn %s would run\n"+
+			"hint: run \"corepack enable\" to use the yarn version of the project",
+			dir, expected, expected.source, installed.source)
+	}
+	return nil
+}
+
+func parseMajor(version string) int {
+	major, _, _ := strings.Cut(strings.TrimPrefix(version, "v"), ".")
+	n, _ := strconv.Atoi(major)
+	return n
+}
@@ -0,0 +1,234 @@
+package conflictresolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/exec"
+)
+
+const (
+	// PluginPrefix is the prefix of executable name of resolver plugin,
+	// the rest of the name is the resolver name
+	PluginPrefix = "dx-resolver-"
+	// PluginDir is the repository local directory of resolver plugins,
+	// relative to the repository root
+	PluginDir = ".dx/resolvers"
+
+	pluginProtocolVersion = 1
+	executableMode        = "100755"
+)
+
+// PluginResolver runs an external executable as conflict resolver. the executable is
+// called with `detect` or `resolve` argument at the repository root, it reads the request
+// from stdin and writes the response to stdout
+//
+// ### Example request
+//
+//	{"version": 1, "action": "detect", "offline": false,
+//	 "files": [{"path": "infra/.terraform.lock.hcl", "kind": "UU"}]}
+//
+// ### Example response
+//
+//	{"detect": true}
+//	{"error": "provider hashes cannot be updated"}
+type PluginResolver struct {
+	name string
+	// path is the executable on PATH, it's empty for the repository plugin
+	path string
+	// blob is the object of the repository plugin in HEAD commit
+	blob    string
+	offline bool
+}
+
+type pluginRequest struct {
+	Version int          `json:"version"`
+	Action  string       `json:"action"`
+	Offline bool         `json:"offline"`
+	Files   []pluginFile `json:"files"`
+}
+
+type pluginFile struct {
+	Path   string    `json:"path"`
+	Kind   string    `json:"kind"`
+	Modes  [3]string `json:"modes"`
+	Hashes [3]string `json:"hashes"`
+}
+
+type pluginResponse struct {
+	Detect bool   `json:"detect"`
+	Error  string `json:"error"`
+}
+
+// FindPlugins returns the resolver plugins in PATH and then the allowed plugins in PluginDir
+// of the repository in root, the first plugin wins when plugins have the same name.
+// the repository plugins are read from HEAD commit, the working tree may have the plugins
+// of the branch that is being merged, which must not run
+func FindPlugins(root string, allowed []string) ([]*PluginResolver, error) {
+	var plugins []*PluginResolver
+	seen := map[string]bool{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := strings.CutPrefix(e.Name(), PluginPrefix)
+			if !ok || name == "" || seen[name] {
+				continue
+			}
+			info, err := os.Stat(filepath.Join(dir, e.Name()))
+			if err != nil || info.IsDir() || info.Mode().Perm()&0111 == 0 {
+				continue
+			}
+			path, err := filepath.Abs(filepath.Join(dir, e.Name()))
+			if err != nil {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, &PluginResolver{name: name, path: path})
+		}
+	}
+	if len(allowed) == 0 {
+		return plugins, nil
+	}
+
+	repoPlugins, err := headPlugins(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range repoPlugins {
+		if seen[p.name] {
+			slog.Warn("repository resolver plugin is shadowed by PATH", "plugin", p.name)
+			continue
+		}
+		if !slices.Contains(allowed, p.name) {
+			slog.Debug("skip repository resolver plugin that not in resolvers.plugins", "plugin", p.name)
+			continue
+		}
+		seen[p.name] = true
+		plugins = append(plugins, p)
+	}
+	return plugins, nil
+}
+
+// headPlugins returns the executables in PluginDir of HEAD commit
+//
+// ### Example output of `git ls-tree -z HEAD -- .dx/resolvers/`
+//
+//	100755 blob 3b18e512dba79e4c8300dd08aeb37f8e728b8dad<TAB>.dx/resolvers/dx-resolver-terraform<NUL>
+func headPlugins(root string) ([]*PluginResolver, error) {
+	out, err := exec.OutputErrDir(root, "git", "ls-tree", "-z", "HEAD", "--", PluginDir+"/")
+	if err != nil {
+		return nil, fmt.Errorf("cannot list resolver plugins of HEAD: %s: %w", out, err)
+	}
+	var plugins []*PluginResolver
+	for _, entry := range strings.Split(out, "\x00") {
+		info, path, ok := strings.Cut(entry, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(info)
+		if len(fields) != 3 || fields[0] != executableMode {
+			continue
+		}
+		name, ok := strings.CutPrefix(filepath.Base(path), PluginPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		plugins = append(plugins, &PluginResolver{name: name, blob: fields[2]})
+	}
+	return plugins, nil
+}
+
+func (r *PluginResolver) Name() string {
+	return r.name
+}
+
+// SetOffline is sent to the plugin, the plugin decides how to resolve without network
+func (r *PluginResolver) SetOffline(offline bool) {
+	r.offline = offline
+}
+
+func (r *PluginResolver) Detect(files []ConflictedFile) bool {
+	resp, err := r.call("detect", files)
+	if err != nil {
+		slog.Warn("resolver plugin cannot detect conflicts", "plugin", r.name, "error", err)
+		return false
+	}
+	return resp.Detect
+}
+
+func (r *PluginResolver) Resolve(files []ConflictedFile) error {
+	_, err := r.call("resolve", files)
+	return err
+}
+
+func (r *PluginResolver) call(action string, files []ConflictedFile) (*pluginResponse, error) {
+	req := pluginRequest{
+		Version: pluginProtocolVersion,
+		Action:  action,
+		Offline: r.offline,
+		Files:   []pluginFile{},
+	}
+	for _, f := range files {
+		req.Files = append(req.Files, pluginFile{
+			Path:   f.Path,
+			Kind:   string(f.Kind),
+			Modes:  f.Modes,
+			Hashes: f.Hashes,
+		})
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	path := r.path
+	if r.blob != "" {
+		path, err = r.extract()
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(path)
+	}
+	stdout, stderr, err := exec.OutputStdin(b, path, action)
+	if err != nil {
+		return nil, fmt.Errorf("resolver plugin %s failed: %s: %w", r.name, strings.TrimSpace(stderr), err)
+	}
+	resp := &pluginResponse{}
+	err = json.Unmarshal([]byte(stdout), resp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response of resolver plugin %s: %q: %w", r.name, stdout, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("resolver plugin %s: %s", r.name, resp.Error)
+	}
+	return resp, nil
+}
+
+// extract writes the repository plugin from HEAD commit into a temp executable
+func (r *PluginResolver) extract() (string, error) {
+	content, stderr, err := exec.OutputStdin(nil, "git", "cat-file", "blob", r.blob)
+	if err != nil {
+		return "", fmt.Errorf("cannot read resolver plugin %s: %s: %w", r.name, stderr, err)
+	}
+	f, err := os.CreateTemp("", PluginPrefix+r.name+"-*")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	_, err = f.WriteString(content)
+	if err == nil {
+		err = f.Chmod(0755)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,69 @@
+package conflictresolver
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindPlugins(t *testing.T) {
+	root := t.TempDir()
+	binDir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, PluginDir), 0755))
+	for _, f := range []struct {
+		path string
+		perm os.FileMode
+	}{
+		{filepath.Join(root, PluginDir, "dx-resolver-terraform"), 0755},
+		{filepath.Join(root, PluginDir, "dx-resolver-lint"), 0755},
+		{filepath.Join(root, PluginDir, "dx-resolver-notes"), 0644},
+		{filepath.Join(root, PluginDir, "dx-resolver-evil"), 0755},
+		{filepath.Join(binDir, "dx-resolver-terraform"), 0755},
+		{filepath.Join(binDir, "dx-resolver-bazel"), 0755},
+		{filepath.Join(binDir, "dx-resolver-notes"), 0644},
+		{filepath.Join(binDir, "dx-resolver-"), 0755},
+		{filepath.Join(binDir, "dx"), 0755},
+	} {
+		require.NoError(t, os.WriteFile(f.path, []byte("#!/bin/sh\n"), f.perm))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(binDir, "dx-resolver-dir"), 0755))
+	for _, args := range [][]string{
+		{"init"},
+		{"add", "."},
+		{"-c", "user.name=dx", "-c", "user.email=dx@example.com", "commit", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	// the plugin that isn't committed must not run
+	require.NoError(t, os.WriteFile(filepath.Join(root, PluginDir, "dx-resolver-wip"), []byte("#!/bin/sh\n"), 0755))
+	git, err := exec.LookPath("git")
+	require.NoError(t, err)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+filepath.Dir(git))
+
+	blob := func(name string) string {
+		cmd := exec.Command("git", "rev-parse", "HEAD:"+PluginDir+"/"+PluginPrefix+name)
+		cmd.Dir = root
+		out, err := cmd.Output()
+		require.NoError(t, err)
+		return string(out[:len(out)-1])
+	}
+	plugins, err := FindPlugins(root, []string{"terraform", "lint", "notes", "wip"})
+	require.NoError(t, err)
+	assert.Equal(t, []*PluginResolver{
+		{name: "bazel", path: filepath.Join(binDir, "dx-resolver-bazel")},
+		{name: "terraform", path: filepath.Join(binDir, "dx-resolver-terraform")},
+		{name: "lint", blob: blob("lint")},
+	}, plugins)
+
+	plugins, err = FindPlugins(root, nil)
+	require.NoError(t, err)
+	assert.Len(t, plugins, 2)
+}
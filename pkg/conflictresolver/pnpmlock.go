@@ -0,0 +1,140 @@
+package conflictresolver
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/conflictfile"
+	"github.com/kitimark/dx/pkg/exec"
+	"gopkg.in/yaml.v3"
+)
+
+type PnpmLockResolver struct{}
+
+var (
+	pnpmLockFileName      = "pnpm-lock.yaml"
+	pnpmWorkspaceFileName = "pnpm-workspace.yaml"
+)
+
+func (r *PnpmLockResolver) Name() string {
+	return "pnpm lock"
+}
+
+// NeedsNetwork is true, pnpm resolves the versions from the registry
+func (r *PnpmLockResolver) NeedsNetwork() bool {
+	return true
+}
+
+func (r *PnpmLockResolver) Detect(files []ConflictedFile) bool {
+	return len(dirsOf(files, pnpmLockFileName, pnpmWorkspaceFileName)) != 0
+}
+
+// Resolve runs `pnpm install --lockfile-only` from each workspace root that
+// pnpm-lock.yaml or pnpm-workspace.yaml is conflicted. pnpm-workspace.yaml and every
+// package.json of the workspace must be resolved first, pnpm merges the conflicted lockfile itself
+func (r *PnpmLockResolver) Resolve(files []ConflictedFile) error {
+	for _, dir := range dirsOf(files, pnpmLockFileName, pnpmWorkspaceFileName) {
+		workspaceFile := filepath.Join(dir, pnpmWorkspaceFileName)
+		isStillConflict, err := conflictfile.IsConflicted(workspaceFile)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if isStillConflict {
+			return fmt.Errorf("%s file is still conflicted, resolve it first", workspaceFile)
+		}
+
+		manifests, err := findPnpmWorkspaceManifests(dir)
+		if err != nil {
+			return err
+		}
+		var stillConflictedFiles []string
+		for _, m := range manifests {
+			isStillConflict, err := conflictfile.IsConflicted(m)
+			if err != nil {
+				return err
+			}
+			if isStillConflict {
+				stillConflictedFiles = append(stillConflictedFiles, m)
+			}
+		}
+		if len(stillConflictedFiles) != 0 {
+			return fmt.Errorf(`package.json files still conflicted, resolve them first
+%s`, strings.Join(stillConflictedFiles, "\n"))
+		}
+
+		slog.Info("run pnpm install --lockfile-only", "dir", dir)
+		out, err := exec.OutputErrDir(dir, "pnpm", "install", "--lockfile-only")
+		if err != nil {
+			slog.Error(out)
+			return err
+		}
+	}
+	return nil
+}
+
+// findPnpmWorkspaceManifests returns package.json of the workspace root and
+// every package that matched by `packages` of pnpm-workspace.yaml
+//
+// ### Example pnpm-workspace.yaml
+//
+//	packages:
+//	  - "packages/*"
+//	  - "apps/**"
+//	  - "!**/test/**"
+func findPnpmWorkspaceManifests(root string) ([]string, error) {
+	var workspace struct {
+		Packages []string `yaml:"packages"`
+	}
+	b, err := os.ReadFile(filepath.Join(root, pnpmWorkspaceFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	err = yaml.Unmarshal(b, &workspace)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", filepath.Join(root, pnpmWorkspaceFileName), err)
+	}
+	var includes, excludes []string
+	for _, p := range workspace.Packages {
+		exclude := strings.HasPrefix(p, "!")
+		p = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(p, "!"), "./"), "/")
+		if exclude {
+			excludes = append(excludes, p)
+		} else {
+			includes = append(includes, p)
+		}
+	}
+
+	manifests := []string{filepath.Join(root, packageJsonFileName)}
+	if len(includes) == 0 {
+		return manifests, nil
+	}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && (d.Name() == "node_modules" || strings.HasPrefix(d.Name(), ".")) {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !matchAnyGlob(includes, rel) || matchAnyGlob(excludes, rel) {
+			return nil
+		}
+		manifest := filepath.Join(path, packageJsonFileName)
+		if _, err := os.Stat(manifest); err == nil {
+			manifests = append(manifests, manifest)
+		}
+		return nil
+	})
+	return manifests, err
+}
@@ -0,0 +1,84 @@
+package conflictresolver
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/kitimark/dx/pkg/config"
+	"github.com/kitimark/dx/pkg/conflictfile"
+)
+
+// PolicyResolutions are the merge policies that PolicyRule can use, every policy
+// of config.Policies must have its resolution
+var PolicyResolutions = map[string]conflictfile.Resolution{
+	config.PolicyUnion:       conflictfile.Union,
+	config.PolicyOurs:        conflictfile.Ours,
+	config.PolicyTheirs:      conflictfile.Theirs,
+	config.PolicySortedUnion: conflictfile.SortedUnion,
+}
+
+// PolicyRule maps globs of the files to the merge policy, the globs are
+// slash separated and relative to the repository root
+type PolicyRule struct {
+	Paths  []string
+	Policy string
+}
+
+// PolicyResolver resolves every hunk of the files with the policy of the first matched rule
+type PolicyResolver struct {
+	Rules []PolicyRule
+}
+
+func (r *PolicyResolver) Name() string {
+	return "policy"
+}
+
+func (r *PolicyResolver) Detect(files []ConflictedFile) bool {
+	for _, f := range files {
+		if _, ok := r.ruleOf(f); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve rewrites the matched files, the files that deleted by one side are left for human
+func (r *PolicyResolver) Resolve(files []ConflictedFile) error {
+	for _, f := range files {
+		rule, ok := r.ruleOf(f)
+		if !ok {
+			continue
+		}
+		if f.Kind.IsDeleted() {
+			slog.Info("skip file that is deleted by one side", "file", f.Path, "kind", f.Kind.String())
+			continue
+		}
+		resolution, ok := PolicyResolutions[rule.Policy]
+		if !ok {
+			return fmt.Errorf("unknown merge policy %q of %s", rule.Policy, f.Path)
+		}
+		cf, err := conflictfile.ReadFile(f.Path)
+		if err != nil {
+			return fmt.Errorf("cannot parse conflict of %s: %w", f.Path, err)
+		}
+		slog.Info("resolve file with merge policy", "file", f.Path, "policy", rule.Policy)
+		err = cf.WriteFile(f.Path, resolution)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PolicyResolver) ruleOf(f ConflictedFile) (PolicyRule, bool) {
+	if f.IsGitlink() {
+		return PolicyRule{}, false
+	}
+	for _, rule := range r.Rules {
+		if matchAnyGlob(rule.Paths, filepath.ToSlash(f.Path)) {
+			return rule, true
+		}
+	}
+	return PolicyRule{}, false
+}
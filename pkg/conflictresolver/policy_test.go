@@ -0,0 +1,34 @@
+package conflictresolver
+
+import (
+	"testing"
+
+	"github.com/kitimark/dx/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyResolver_RuleOf(t *testing.T) {
+	r := &PolicyResolver{Rules: []PolicyRule{
+		{Paths: []string{"CHANGELOG.md", "**/CHANGELOG.md"}, Policy: "union"},
+		{Paths: []string{"locales/*.txt"}, Policy: "sorted-union"},
+		{Paths: []string{"**/*.txt"}, Policy: "theirs"},
+	}}
+	for path, policy := range map[string]string{
+		"CHANGELOG.md":         "union",
+		"docs/CHANGELOG.md":    "union",
+		"locales/en.txt":       "sorted-union",
+		"locales/th/words.txt": "theirs",
+		"README.md":            "",
+	} {
+		rule, ok := r.ruleOf(ConflictedFile{Path: path})
+		assert.Equal(t, policy != "", ok, path)
+		assert.Equal(t, policy, rule.Policy, path)
+	}
+}
+
+func TestPolicyResolutions(t *testing.T) {
+	for _, policy := range config.Policies {
+		assert.Contains(t, PolicyResolutions, policy)
+	}
+	assert.Len(t, PolicyResolutions, len(config.Policies))
+}
@@ -0,0 +1,113 @@
+package conflictresolver
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/exec"
+)
+
+// PythonLockResolver regenerates the lockfile of python package manager with its
+// lock-only command. the lockfile embeds hash of the manifest, so it cannot be merged by hand
+type PythonLockResolver struct {
+	name             string
+	lockFileName     string
+	manifestFileName string
+	// lockCommand returns the command that locks the project in dir without installing
+	lockCommand func(dir string) ([]string, error)
+}
+
+var (
+	PoetryLockResolver = &PythonLockResolver{
+		name:             "poetry lock",
+		lockFileName:     "poetry.lock",
+		manifestFileName: "pyproject.toml",
+		lockCommand:      poetryLockCommand,
+	}
+	UvLockResolver = &PythonLockResolver{
+		name:             "uv lock",
+		lockFileName:     "uv.lock",
+		manifestFileName: "pyproject.toml",
+		lockCommand: func(string) ([]string, error) {
+			return []string{"uv", "lock"}, nil
+		},
+	}
+	PipenvLockResolver = &PythonLockResolver{
+		name:             "pipenv lock",
+		lockFileName:     "Pipfile.lock",
+		manifestFileName: "Pipfile",
+		lockCommand: func(string) ([]string, error) {
+			return []string{"pipenv", "lock"}, nil
+		},
+	}
+)
+
+func (r *PythonLockResolver) Name() string {
+	return r.name
+}
+
+// NeedsNetwork is true, the lock command resolves the versions from the package index
+func (r *PythonLockResolver) NeedsNetwork() bool {
+	return true
+}
+
+func (r *PythonLockResolver) Detect(files []ConflictedFile) bool {
+	return len(dirsOf(files, r.lockFileName)) != 0
+}
+
+// Resolve runs the lock command in each directory that the lockfile is conflicted,
+// the manifest of the directory and the conflicted manifests under it must be resolved first
+func (r *PythonLockResolver) Resolve(files []ConflictedFile) error {
+	for _, dir := range dirsOf(files, r.lockFileName) {
+		err := checkManifestConflictedIsResolved(files, dir, r.manifestFileName)
+		if err != nil {
+			return err
+		}
+
+		// the lock command reads the locked versions, start from ours
+		lockFile := filepath.Join(dir, r.lockFileName)
+		b, err := readOurs(lockFile)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(lockFile, b, 0644)
+		if err != nil {
+			return err
+		}
+
+		command, err := r.lockCommand(dir)
+		if err != nil {
+			return err
+		}
+		slog.Info("run "+strings.Join(command, " "), "dir", dir)
+		out, err := exec.OutputErrDir(dir, command[0], command[1:]...)
+		if err != nil {
+			slog.Error(out)
+			return err
+		}
+	}
+	return nil
+}
+
+// poetryLockCommand keeps the locked versions, it's the default since Poetry 2
+//
+// ### Example output of `poetry --version`
+//
+//	Poetry (version 1.8.3)
+func poetryLockCommand(dir string) ([]string, error) {
+	out, err := exec.OutputErrDir(dir, "poetry", "--version")
+	if err != nil {
+		return nil, fmt.Errorf("cannot get poetry version: %s: %w", out, err)
+	}
+	version := strings.TrimSuffix(strings.TrimSpace(out), ")")
+	if i := strings.LastIndex(version, " "); i != -1 {
+		version = version[i+1:]
+	}
+	if parseMajor(version) < 2 {
+		return []string{"poetry", "lock", "--no-update"}, nil
+	}
+	return []string{"poetry", "lock"}, nil
+}
@@ -0,0 +1,77 @@
+package conflictresolver
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/conflictfile"
+	"github.com/kitimark/dx/pkg/exec"
+)
+
+const (
+	stageBase   = 1
+	stageOurs   = 2
+	stageTheirs = 3
+)
+
+var errNotInIndex = errors.New("file has no conflict stages in the index")
+
+// readIndexStages returns the content of path at base, ours and theirs stage
+// of the index, the content is nil if the stage doesn't exist
+//
+// ### Example output of `git ls-files -u -- go.mod`
+//
+//	100644 5716ca5987cbf97d6bb54920bea6adde242d87e6 1	go.mod
+//	100644 0c90d2b9e34bc6e0e4e1b0dbeb1a8aba0b2f9c16 2	go.mod
+//	100644 ad0e6b7c1e4b9a2c1f0c4b0e1b7b0c0b2b3e6e01 3	go.mod
+func readIndexStages(path string) (stages [4][]byte, err error) {
+	out, err := exec.OutputErr("git", "ls-files", "-u", "--", path)
+	if err != nil {
+		return stages, fmt.Errorf("error during list index stages of %s: %s: %w", path, out, err)
+	}
+	found := false
+	for _, line := range strings.Split(out, "\n") {
+		info, _, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(info)
+		if len(fields) != 3 {
+			continue
+		}
+		var stage int
+		_, err = fmt.Sscan(fields[2], &stage)
+		if err != nil || stage < stageBase || stage > stageTheirs {
+			continue
+		}
+		// stderr of git, e.g. a warning, must not be mixed into the content
+		b, stderr, err := exec.OutputStdin(nil, "git", "cat-file", "blob", fields[1])
+		if err != nil {
+			return stages, fmt.Errorf("cannot read stage %d of %s: %s: %w", stage, path, stderr, err)
+		}
+		stages[stage] = []byte(b)
+		found = true
+	}
+	if !found {
+		return stages, errNotInIndex
+	}
+	return stages, nil
+}
+
+// readOurs returns content of ours side of the conflicted file, from the index
+// stage or from the conflict markers when the stages are gone
+func readOurs(path string) ([]byte, error) {
+	stages, err := readIndexStages(path)
+	if err == nil && stages[stageOurs] != nil {
+		return stages[stageOurs], nil
+	}
+	if err != nil && !errors.Is(err, errNotInIndex) {
+		return nil, err
+	}
+	cf, err := conflictfile.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse conflict of %s: %w", path, err)
+	}
+	return cf.Resolve(conflictfile.Ours), nil
+}
@@ -0,0 +1,399 @@
+package conflictresolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/conflictfile"
+	"gopkg.in/yaml.v3"
+)
+
+// structuredSkippedFileNames are the lockfiles that regenerated by their resolvers
+var structuredSkippedFileNames = []string{"package-lock.json", "npm-shrinkwrap.json", "pnpm-lock.yaml"}
+
+// StructuredResolver merges JSON and YAML files key by key from the index stages,
+// the files that have keys changed on both sides are left for human
+type StructuredResolver struct{}
+
+func (r *StructuredResolver) Name() string {
+	return "json yaml"
+}
+
+func (r *StructuredResolver) Detect(files []ConflictedFile) bool {
+	return len(structuredFilesOf(files)) != 0
+}
+
+func (r *StructuredResolver) Resolve(files []ConflictedFile) error {
+	for _, f := range structuredFilesOf(files) {
+		// the file might be resolved by the policy resolver
+		isStillConflict, err := conflictfile.IsConflicted(f.Path)
+		if err != nil {
+			return err
+		}
+		if !isStillConflict {
+			continue
+		}
+		stages, err := readIndexStages(f.Path)
+		if errors.Is(err, errNotInIndex) {
+			slog.Info("skip file that is not in the index stages", "file", f.Path)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		b, conflicts, err := mergeStructured(f.Path, stages[stageBase], stages[stageOurs], stages[stageTheirs])
+		if err != nil {
+			slog.Info("skip file that cannot be merged structurally", "file", f.Path, "error", err)
+			continue
+		}
+		if len(conflicts) != 0 {
+			slog.Warn("keys are changed on both sides, resolve them manually",
+				"file", f.Path, "paths", strings.Join(conflicts, ", "))
+			continue
+		}
+		err = os.WriteFile(f.Path, b, 0644)
+		if err != nil {
+			return err
+		}
+		slog.Info("merge file key by key", "file", f.Path)
+	}
+	return nil
+}
+
+func structuredFilesOf(files []ConflictedFile) []ConflictedFile {
+	var structuredFiles []ConflictedFile
+	for _, f := range files {
+		if f.Kind.IsDeleted() || slices.Contains(structuredSkippedFileNames, filepath.Base(f.Path)) {
+			continue
+		}
+		switch filepath.Ext(f.Path) {
+		case ".json", ".yaml", ".yml":
+			structuredFiles = append(structuredFiles, f)
+		}
+	}
+	return structuredFiles
+}
+
+// mergeStructured merges JSON or YAML documents by the extension of path, base is nil
+// when both sides added the file. it returns JSON paths of the keys that changed on both sides
+func mergeStructured(path string, base, ours, theirs []byte) ([]byte, []string, error) {
+	if ours == nil || theirs == nil {
+		return nil, nil, errors.New("file is deleted by one side")
+	}
+	baseDocs, err := decodeYAMLDocuments(base)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot parse base: %w", err)
+	}
+	oursDocs, err := decodeYAMLDocuments(ours)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot parse ours: %w", err)
+	}
+	theirsDocs, err := decodeYAMLDocuments(theirs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot parse theirs: %w", err)
+	}
+
+	m := &structuredMerge{}
+	var docs []*yaml.Node
+	switch {
+	case len(oursDocs) == len(theirsDocs) && (base == nil || len(baseDocs) == len(oursDocs)):
+		for i := range oursDocs {
+			var baseDoc *yaml.Node
+			if base != nil {
+				baseDoc = baseDocs[i].Content[0]
+			}
+			m.document = ""
+			if len(oursDocs) > 1 {
+				m.document = fmt.Sprintf(" (document %d)", i+1)
+			}
+			oursDocs[i].Content[0] = m.merge("$", baseDoc, oursDocs[i].Content[0], theirsDocs[i].Content[0])
+		}
+		docs = oursDocs
+	case base != nil && equalNodes(baseDocs, oursDocs):
+		docs = theirsDocs
+	case base != nil && equalNodes(baseDocs, theirsDocs):
+		docs = oursDocs
+	default:
+		return nil, []string{"$ (documents are added or removed on both sides)"}, nil
+	}
+	if len(m.conflicts) != 0 {
+		return nil, m.conflicts, nil
+	}
+
+	if filepath.Ext(path) == ".json" {
+		if len(docs) != 1 {
+			return nil, nil, errors.New("json file must have one value")
+		}
+		var b bytes.Buffer
+		err = encodeJSONNode(&b, docs[0].Content[0], detectIndent(ours, "  "), "")
+		if err != nil {
+			return nil, nil, err
+		}
+		if bytes.HasSuffix(ours, []byte("\n")) {
+			b.WriteString("\n")
+		}
+		return b.Bytes(), nil, nil
+	}
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(len(detectIndent(ours, "  ")))
+	for _, doc := range docs {
+		err = enc.Encode(doc)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	err = enc.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.Bytes(), nil, nil
+}
+
+func decodeYAMLDocuments(b []byte) ([]*yaml.Node, error) {
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		doc := &yaml.Node{}
+		err := dec.Decode(doc)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(doc.Content) == 0 {
+			return nil, errors.New("empty document")
+		}
+		docs = append(docs, doc)
+	}
+}
+
+type structuredMerge struct {
+	// document is the suffix of conflict path when the file has multiple documents
+	document  string
+	conflicts []string
+}
+
+// merge returns the merged value of ours and theirs, base is nil when the key is
+// added on both sides. mappings are merged key by key, other values are merged as a whole
+func (m *structuredMerge) merge(path string, base, ours, theirs *yaml.Node) *yaml.Node {
+	switch {
+	case equalNode(ours, theirs):
+		return ours
+	case base != nil && equalNode(base, ours):
+		return theirs
+	case base != nil && equalNode(base, theirs):
+		return ours
+	case ours.Kind == yaml.MappingNode && theirs.Kind == yaml.MappingNode &&
+		(base == nil || base.Kind == yaml.MappingNode):
+		m.mergeMapping(path, base, ours, theirs)
+		return ours
+	}
+	m.conflicts = append(m.conflicts, path+m.document)
+	return ours
+}
+
+// mergeMapping merges theirs into ours, the new keys of theirs are placed
+// after the key that they follow in theirs
+func (m *structuredMerge) mergeMapping(path string, base, ours, theirs *yaml.Node) {
+	for _, key := range mappingKeys(ours) {
+		o := mappingValue(ours, key)
+		t := mappingValue(theirs, key)
+		b := mappingValue(base, key)
+		childPath := jsonPath(path, key)
+		switch {
+		case t != nil:
+			setMappingValue(ours, key, m.merge(childPath, b, o, t))
+		case b == nil:
+			// added by ours
+		case equalNode(b, o):
+			deleteMappingKey(ours, key)
+		default:
+			m.conflicts = append(m.conflicts, childPath+m.document)
+		}
+	}
+
+	var after string
+	for _, key := range mappingKeys(theirs) {
+		if mappingValue(ours, key) != nil {
+			after = key
+			continue
+		}
+		b := mappingValue(base, key)
+		t := mappingValue(theirs, key)
+		switch {
+		case b == nil:
+			insertMappingKey(ours, after, mappingKey(theirs, key), t)
+			after = key
+		case !equalNode(b, t):
+			m.conflicts = append(m.conflicts, jsonPath(path, key)+m.document)
+		}
+	}
+}
+
+func mappingKeys(n *yaml.Node) []string {
+	var keys []string
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		keys = append(keys, n.Content[i].Value)
+	}
+	return keys
+}
+
+func mappingIndex(n *yaml.Node, key string) int {
+	if n == nil {
+		return -1
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func mappingKey(n *yaml.Node, key string) *yaml.Node {
+	if i := mappingIndex(n, key); i != -1 {
+		return n.Content[i]
+	}
+	return nil
+}
+
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if i := mappingIndex(n, key); i != -1 {
+		return n.Content[i+1]
+	}
+	return nil
+}
+
+func setMappingValue(n *yaml.Node, key string, v *yaml.Node) {
+	if i := mappingIndex(n, key); i != -1 {
+		n.Content[i+1] = v
+	}
+}
+
+func deleteMappingKey(n *yaml.Node, key string) {
+	if i := mappingIndex(n, key); i != -1 {
+		n.Content = slices.Delete(n.Content, i, i+2)
+	}
+}
+
+// insertMappingKey inserts the pair after the key, or at the beginning when after is empty
+func insertMappingKey(n *yaml.Node, after string, k, v *yaml.Node) {
+	i := 0
+	if j := mappingIndex(n, after); after != "" && j != -1 {
+		i = j + 2
+	}
+	n.Content = slices.Insert(n.Content, i, k, v)
+}
+
+// equalNode compares the values of nodes, comments and styles are ignored
+func equalNode(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind != b.Kind {
+		return false
+	}
+	switch a.Kind {
+	case yaml.ScalarNode:
+		return a.ShortTag() == b.ShortTag() && a.Value == b.Value
+	case yaml.AliasNode:
+		return a.Value == b.Value
+	}
+	return equalNodes(a.Content, b.Content)
+}
+
+func equalNodes(a, b []*yaml.Node) bool {
+	return slices.EqualFunc(a, b, equalNode)
+}
+
+var jsonIdentifierRegex = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// jsonPath returns path of key in its parent, e.g. $.scripts.build and $.dependencies["@babel/core"]
+func jsonPath(parent, key string) string {
+	if jsonIdentifierRegex.MatchString(key) {
+		return parent + "." + key
+	}
+	b, _ := json.Marshal(key)
+	return parent + "[" + string(b) + "]"
+}
+
+// detectIndent returns the leading whitespace of the first indented line
+func detectIndent(b []byte, defaultIndent string) string {
+	for _, line := range strings.Split(string(b), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) != len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return defaultIndent
+}
+
+// encodeJSONNode writes the node that parsed from JSON back in JSON, the key order
+// and the text of numbers are kept
+func encodeJSONNode(w *bytes.Buffer, n *yaml.Node, indent, prefix string) error {
+	switch n.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		open, close, step := "{", "}", 2
+		if n.Kind == yaml.SequenceNode {
+			open, close, step = "[", "]", 1
+		}
+		if len(n.Content) == 0 {
+			w.WriteString(open + close)
+			return nil
+		}
+		w.WriteString(open + "\n")
+		for i := 0; i < len(n.Content); i += step {
+			w.WriteString(prefix + indent)
+			if step == 2 {
+				err := encodeJSONString(w, n.Content[i].Value)
+				if err != nil {
+					return err
+				}
+				w.WriteString(": ")
+			}
+			err := encodeJSONNode(w, n.Content[i+step-1], indent, prefix+indent)
+			if err != nil {
+				return err
+			}
+			if i+step < len(n.Content) {
+				w.WriteString(",")
+			}
+			w.WriteString("\n")
+		}
+		w.WriteString(prefix + close)
+		return nil
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!null", "!!bool", "!!int", "!!float":
+			w.WriteString(n.Value)
+			return nil
+		}
+		return encodeJSONString(w, n.Value)
+	}
+	return fmt.Errorf("line %d: unsupported json value", n.Line)
+}
+
+func encodeJSONString(w *bytes.Buffer, s string) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(s)
+	if err != nil {
+		return err
+	}
+	w.Write(bytes.TrimSuffix(b.Bytes(), []byte("\n")))
+	return nil
+}
@@ -0,0 +1,133 @@
+package conflictresolver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeStructured_JSON(t *testing.T) {
+	base := `{
+	"name": "app",
+	"scripts": {
+		"build": "tsc"
+	},
+	"dependencies": {
+		"react": "^18.0.0"
+	},
+	"private": true
+}
+`
+	ours := `{
+	"name": "app",
+	"scripts": {
+		"build": "tsc",
+		"lint": "eslint ."
+	},
+	"dependencies": {
+		"@babel/core": "^7.0.0",
+		"react": "^18.0.0"
+	},
+	"private": true
+}
+`
+	theirs := `{
+	"name": "app",
+	"version": "1.0.0",
+	"scripts": {
+		"build": "tsc -b"
+	},
+	"dependencies": {
+		"react": "^18.0.0",
+		"zod": "^3.0.0"
+	}
+}
+`
+	b, conflicts, err := mergeStructured("package.json", []byte(base), []byte(ours), []byte(theirs))
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	assert.Equal(t, `{
+	"name": "app",
+	"version": "1.0.0",
+	"scripts": {
+		"build": "tsc -b",
+		"lint": "eslint ."
+	},
+	"dependencies": {
+		"@babel/core": "^7.0.0",
+		"react": "^18.0.0",
+		"zod": "^3.0.0"
+	}
+}
+`, string(b))
+}
+
+func TestMergeStructured_JSONConflict(t *testing.T) {
+	base := `{"dependencies": {"@babel/core": "7.0.0", "react": "18.0.0"}, "version": 1}`
+	ours := `{"dependencies": {"@babel/core": "7.1.0", "react": "18.1.0"}, "version": 2}`
+	theirs := `{"dependencies": {"@babel/core": "7.2.0", "react": "18.1.0"}}`
+	_, conflicts, err := mergeStructured("package.json", []byte(base), []byte(ours), []byte(theirs))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`$.dependencies["@babel/core"]`, "$.version"}, conflicts)
+}
+
+func TestMergeStructured_YAML(t *testing.T) {
+	base := `apiVersion: apps/v1
+kind: Deployment
+spec:
+  replicas: 1
+---
+apiVersion: v1
+kind: Service
+`
+	ours := `apiVersion: apps/v1
+kind: Deployment
+spec:
+  # scaled for the launch
+  replicas: 3
+---
+apiVersion: v1
+kind: Service
+`
+	theirs := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+---
+apiVersion: v1
+kind: Service
+spec:
+  type: ClusterIP
+`
+	b, conflicts, err := mergeStructured("deploy.yaml", []byte(base), []byte(ours), []byte(theirs))
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  # scaled for the launch
+  replicas: 3
+---
+apiVersion: v1
+kind: Service
+spec:
+  type: ClusterIP
+`, string(b))
+
+	theirs = `apiVersion: apps/v1
+kind: Deployment
+spec:
+  replicas: 2
+---
+apiVersion: v1
+kind: Service
+`
+	_, conflicts, err = mergeStructured("deploy.yaml", []byte(base), []byte(ours), []byte(theirs))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"$.spec.replicas (document 1)"}, conflicts)
+}
@@ -0,0 +1,91 @@
+package conflictresolver
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/exec"
+)
+
+// SubmoduleResolver resolves the conflicted submodule pointers. the pointer is fast-forwarded
+// to the descendant commit when one side is an ancestor of the other, and only the index is
+// updated, so the submodule worktree is kept as it is
+type SubmoduleResolver struct{}
+
+func (r *SubmoduleResolver) Name() string {
+	return "submodule"
+}
+
+func (r *SubmoduleResolver) Detect(files []ConflictedFile) bool {
+	for _, f := range files {
+		if f.IsGitlink() {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve stages the descendant commit of each conflicted submodule, the diverged
+// submodules and the submodules that are deleted or replaced by one side are left for human
+func (r *SubmoduleResolver) Resolve(files []ConflictedFile) error {
+	for _, f := range files {
+		if !f.IsGitlink() {
+			continue
+		}
+		ours, theirs := f.Hashes[stageOurs-1], f.Hashes[stageTheirs-1]
+		if f.Modes[stageOurs-1] != gitlinkMode || f.Modes[stageTheirs-1] != gitlinkMode {
+			slog.Warn("submodule is deleted or replaced by one side, resolve it manually",
+				"submodule", f.Path, "kind", f.Kind.String())
+			continue
+		}
+
+		commit, err := descendantCommit(f.Path, ours, theirs)
+		if err != nil {
+			return err
+		}
+		if commit == "" {
+			slog.Warn("submodule commits have diverged, merge them in the submodule and stage the result",
+				"submodule", f.Path, "ours", ours, "theirs", theirs)
+			continue
+		}
+
+		out, err := exec.OutputErr("git", "update-index", "--cacheinfo", gitlinkMode+","+commit+","+f.Path)
+		if err != nil {
+			return fmt.Errorf("cannot stage submodule %s: %s: %w", f.Path, out, err)
+		}
+		slog.Info("fast-forward submodule, run `git submodule update` to check it out",
+			"submodule", f.Path, "commit", commit)
+	}
+	return nil
+}
+
+// descendantCommit returns the commit of ours or theirs that contains the other,
+// it's empty when they have diverged. the commits are looked up in the submodule in path
+func descendantCommit(path, ours, theirs string) (string, error) {
+	// git runs in the superproject when the submodule is not checked out
+	prefix, err := exec.OutputErrDir(path, "git", "rev-parse", "--show-prefix")
+	if err != nil || strings.TrimSpace(prefix) != "" {
+		return "", fmt.Errorf("submodule %s is not checked out, run `git submodule update --init %s`", path, path)
+	}
+	for _, c := range []string{ours, theirs} {
+		out, err := exec.OutputErrDir(path, "git", "cat-file", "-e", c+"^{commit}")
+		if err != nil {
+			return "", fmt.Errorf("commit %s is not found in submodule %s, run `git submodule update --init` "+
+				"or fetch it in the submodule: %s: %w", c, path, out, err)
+		}
+	}
+	if isAncestor(path, theirs, ours) {
+		return ours, nil
+	}
+	if isAncestor(path, ours, theirs) {
+		return theirs, nil
+	}
+	return "", nil
+}
+
+// isAncestor returns true if ancestor is reachable from commit in the repository in dir
+func isAncestor(dir, ancestor, commit string) bool {
+	_, err := exec.OutputErrDir(dir, "git", "merge-base", "--is-ancestor", ancestor, commit)
+	return err == nil
+}
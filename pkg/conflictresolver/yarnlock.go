@@ -2,9 +2,12 @@ package conflictresolver
 
 import (
 	"fmt"
-	"github.com/kitimark/dx/pkg/exec"
 	"log/slog"
+	"path/filepath"
 	"slices"
+
+	"github.com/kitimark/dx/pkg/conflictfile"
+	"github.com/kitimark/dx/pkg/exec"
 )
 
 type YarnLockResolver struct{}
@@ -18,25 +21,77 @@ func (r *YarnLockResolver) Name() string {
 	return "yarn lock"
 }
 
-func (r *YarnLockResolver) Detect(fileNames []string) bool {
-	return slices.Contains(fileNames, yarnLockFileName)
+// NeedsNetwork is true, yarn fetches the package metadata to update the lockfile
+func (r *YarnLockResolver) NeedsNetwork() bool {
+	return true
 }
 
-func (r *YarnLockResolver) Resolve(fileNames []string) error {
-	isStillConflict, err := isContentStillConflict(packageJsonFileName)
-	if err != nil {
-		return err
-	}
-	if isStillConflict {
-		return fmt.Errorf("package.json file is still conflicted, resolve them first")
-	}
+func (r *YarnLockResolver) Detect(files []ConflictedFile) bool {
+	return len(dirsOf(files, yarnLockFileName)) != 0
+}
+
+// Resolve runs yarn in each directory that its yarn.lock is conflicted, package.json
+// of the directory and the conflicted package.json of its workspaces must be resolved first.
+// Yarn 1 runs a full install, Yarn 2 and later only update the lockfile
+func (r *YarnLockResolver) Resolve(files []ConflictedFile) error {
+	for _, dir := range dirsOf(files, yarnLockFileName) {
+		err := checkPackageJsonConflictedIsResolved(files, dir)
+		if err != nil {
+			return err
+		}
+
+		expected, err := detectYarn(dir)
+		if err != nil {
+			return err
+		}
+		installed, err := installedYarn(dir)
+		if err != nil {
+			return err
+		}
+		err = checkYarn(dir, expected, installed)
+		if err != nil {
+			return err
+		}
 
-	slog.Info("try to run yarn again")
-	out, err := exec.OutputErr("yarn")
-	if err != nil {
-		slog.Error(out)
-		return err
+		args := []string{}
+		if installed.berry {
+			args = []string{"install", "--mode=update-lockfile"}
+		}
+		slog.Info("try to run yarn again", "dir", dir, "yarn", installed.source)
+		out, err := exec.OutputErrDir(dir, "yarn", args...)
+		if err != nil {
+			slog.Error(out)
+			return err
+		}
 	}
 
 	return nil
 }
+
+// checkPackageJsonConflictedIsResolved checks package.json in dir and
+// every conflicted package.json under dir
+func checkPackageJsonConflictedIsResolved(files []ConflictedFile, dir string) error {
+	return checkManifestConflictedIsResolved(files, dir, packageJsonFileName)
+}
+
+// checkManifestConflictedIsResolved checks manifest file in dir and every
+// conflicted manifest file with the same name under dir
+func checkManifestConflictedIsResolved(files []ConflictedFile, dir string, manifestFileName string) error {
+	manifests := []string{filepath.Join(dir, manifestFileName)}
+	for _, f := range files {
+		if filepath.Base(f.Path) == manifestFileName && isUnderDir(f.Path, dir) &&
+			!slices.Contains(manifests, f.Path) {
+			manifests = append(manifests, f.Path)
+		}
+	}
+	for _, m := range manifests {
+		isStillConflict, err := conflictfile.IsConflicted(m)
+		if err != nil {
+			return err
+		}
+		if isStillConflict {
+			return fmt.Errorf("%s file is still conflicted, resolve them first", m)
+		}
+	}
+	return nil
+}
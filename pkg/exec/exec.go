@@ -1,13 +1,68 @@
 package exec
 
 import (
+	"bytes"
+	"context"
 	"log/slog"
+	"os"
 	"os/exec"
+	"time"
 )
 
+// waitDelay is the time to wait for the command to exit after it's interrupted
+const waitDelay = 5 * time.Second
+
 func OutputErr(command string, args ...string) (string, error) {
+	return OutputErrContext(context.Background(), command, args...)
+}
+
+// OutputErrDir is like OutputErr but runs the command in dir
+func OutputErrDir(dir string, command string, args ...string) (string, error) {
 	cmd := exec.Command(command, args...)
-	slog.Debug("exec command", "cmd", cmd.String())
+	cmd.Dir = dir
+	return combinedOutput(cmd)
+}
+
+// OutputErrDirEnv is like OutputErrDir but appends env to the environment of the command,
+// env is in form of "key=value"
+func OutputErrDirEnv(dir string, env []string, command string, args ...string) (string, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	slog.Debug("exec env", "env", env)
+	return combinedOutput(cmd)
+}
+
+// OutputErrContext is like OutputErr but interrupts the command when ctx is done
+func OutputErrContext(ctx context.Context, command string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Cancel = func() error {
+		err := cmd.Process.Signal(os.Interrupt)
+		if err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = waitDelay
+	return combinedOutput(cmd)
+}
+
+// OutputStdin is like OutputErr but writes stdin to the command, stdout and stderr are
+// returned separately so stdout can be parsed
+func OutputStdin(stdin []byte, command string, args ...string) (stdout string, stderr string, err error) {
+	cmd := exec.Command(command, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var outBuf, errBuf bytes.Buffer
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &errBuf
+	slog.Debug("exec command", "cmd", cmd.String(), "stdin", string(stdin))
+	err = cmd.Run()
+	slog.Debug("exec result", "stdout", outBuf.String(), "stderr", errBuf.String())
+	return outBuf.String(), errBuf.String(), err
+}
+
+func combinedOutput(cmd *exec.Cmd) (string, error) {
+	slog.Debug("exec command", "cmd", cmd.String(), "dir", cmd.Dir)
 	b, err := cmd.CombinedOutput()
 	slog.Debug("exec result", "result", string(b))
 	return string(b), err
@@ -1,11 +1,16 @@
 package dx
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"os"
 	"slices"
 	"strings"
 
+	"github.com/kitimark/dx/pkg/conflictfile"
 	"github.com/kitimark/dx/pkg/conflictresolver"
 	"github.com/kitimark/dx/pkg/exec"
 	"github.com/spf13/cobra"
@@ -17,16 +22,63 @@ func NewResolveConflictCmd() *cobra.Command {
 		Aliases: []string{"resolve"},
 		RunE:    cmdResolveConflictRun,
 	}
+	cmd.PersistentFlags().Bool("offline", false, "resolve without network access, the resolvers that need network are skipped")
 	return cmd
 }
 
 func cmdResolveConflictRun(cmd *cobra.Command, _ []string) error {
+	offline, err := cmd.Flags().GetBool("offline")
+	if err != nil {
+		return err
+	}
+
+	// conflicted files are relative to the repository root,
+	// resolvers run there so they work from any subdirectory
+	root, err := getRepoRoot()
+	if err != nil {
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	err = os.Chdir(root)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := os.Chdir(wd)
+		if err != nil {
+			slog.Warn("cannot change back to working directory", "dir", wd, "error", err)
+		}
+	}()
+
 	conflictedFiles, err := getConflictedFiles()
 	if err != nil {
 		return err
 	}
+	markedFiles, err := markedConflictedFiles(conflictedFiles)
+	if err != nil {
+		return err
+	}
+	resolvedFiles := map[string]bool{}
 
-	for _, r := range conflictresolver.ConflictResolvers {
+	resolvers, err := conflictResolvers()
+	if err != nil {
+		return err
+	}
+	for _, r := range resolvers {
+		if len(cfg.Resolvers.Enabled) != 0 && !slices.Contains(cfg.Resolvers.Enabled, r.Name()) ||
+			slices.Contains(cfg.Resolvers.Disabled, r.Name()) {
+			slog.Debug("skip disabled resolver", "resolver", r.Name())
+			continue
+		}
+		if or, ok := r.(conflictresolver.OfflineResolver); ok {
+			or.SetOffline(offline)
+		} else if nr, ok := r.(conflictresolver.NetworkResolver); ok && offline && nr.NeedsNetwork() {
+			slog.Warn("skip resolver that needs network in offline mode", "resolver", r.Name())
+			continue
+		}
 		if r.Detect(conflictedFiles) {
 			slog.Info(fmt.Sprintf("detect %s conflict, trying to resolve", r.Name()))
 			err = r.Resolve(conflictedFiles)
@@ -34,36 +86,175 @@ func cmdResolveConflictRun(cmd *cobra.Command, _ []string) error {
 				cmd.SilenceUsage = true
 				return err
 			}
+			conflictedFiles, err = refreshConflictedFiles(markedFiles, resolvedFiles)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
-var gitXYConflictedStatuses = []string{"AA", "UU"}
+// conflictResolvers returns the built-in resolvers with the resolvers from config and the
+// resolver plugins. by default the policy resolver runs first and then the generate resolver,
+// so the specialised resolvers see the resolved and regenerated files. the plugins run
+// after the built-in resolvers and migrations are renumbered last, see resolvers.order config
+func conflictResolvers() ([]conflictresolver.ConflictResolver, error) {
+	var resolvers []conflictresolver.ConflictResolver
+	if len(cfg.Resolvers.Policy) != 0 {
+		policy := &conflictresolver.PolicyResolver{}
+		for _, r := range cfg.Resolvers.Policy {
+			policy.Rules = append(policy.Rules, conflictresolver.PolicyRule{
+				Paths:  r.Paths,
+				Policy: r.Policy,
+			})
+		}
+		resolvers = append(resolvers, policy)
+	}
+	if len(cfg.Resolvers.Generate) != 0 {
+		generate := &conflictresolver.GenerateResolver{}
+		for _, r := range cfg.Resolvers.Generate {
+			generate.Rules = append(generate.Rules, conflictresolver.GenerateRule{
+				Name:    r.Name,
+				Outputs: r.Outputs,
+				Inputs:  r.Inputs,
+				Command: r.Command,
+			})
+		}
+		resolvers = append(resolvers, generate)
+	}
+	resolvers = append(resolvers, conflictresolver.ConflictResolvers...)
+	plugins, err := conflictresolver.FindPlugins(".", cfg.Resolvers.Plugins)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range plugins {
+		resolvers = append(resolvers, p)
+	}
+	if len(cfg.Resolvers.Migrations) != 0 {
+		resolvers = append(resolvers, &conflictresolver.MigrationResolver{
+			Dirs:    cfg.Resolvers.Migrations,
+			BaseRef: migrationBaseRef(),
+		})
+	}
+
+	rank := func(r conflictresolver.ConflictResolver) int {
+		i := slices.Index(cfg.Resolvers.Order, r.Name())
+		if i == -1 {
+			return len(cfg.Resolvers.Order)
+		}
+		return i
+	}
+	slices.SortStableFunc(resolvers, func(a, b conflictresolver.ConflictResolver) int {
+		return cmp.Compare(rank(a), rank(b))
+	})
+	return resolvers, nil
+}
+
+// migrationBaseRef returns HEAD while merge, rebase or cherry-pick is stopped, so the
+// migrations of the other side are incoming. otherwise the migrations of the current
+// branch are incoming to the main branch, e.g. after rebase
+func migrationBaseRef() string {
+	for _, ref := range []string{"MERGE_HEAD", "REBASE_HEAD", "CHERRY_PICK_HEAD", "REVERT_HEAD"} {
+		_, err := exec.OutputErr("git", "rev-parse", "--quiet", "--verify", ref)
+		if err == nil {
+			return "HEAD"
+		}
+	}
+	return mainBranchName
+}
+
+// markedConflictedFiles returns paths of the conflicted files that have conflict markers,
+// the deleted files, the submodules and the binary files don't have them
+func markedConflictedFiles(files []conflictresolver.ConflictedFile) (map[string]bool, error) {
+	marked := map[string]bool{}
+	for _, f := range files {
+		if f.Kind.IsDeleted() || f.IsGitlink() {
+			continue
+		}
+		isConflicted, err := conflictfile.IsConflicted(f.Path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if isConflicted {
+			marked[f.Path] = true
+		}
+	}
+	return marked, nil
+}
 
-// getConflictedFiles return list of conflict files that parsed from `git status --short`
+// refreshConflictedFiles lists the conflicted files again after a resolver runs, so the next
+// resolvers don't resolve the same files. the resolvers don't stage their results, a marked
+// file is resolved when its conflict markers are gone and it's kept in resolved
+func refreshConflictedFiles(marked, resolved map[string]bool) ([]conflictresolver.ConflictedFile, error) {
+	for path := range marked {
+		isConflicted, err := conflictfile.IsConflicted(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if !isConflicted {
+			delete(marked, path)
+			resolved[path] = true
+		}
+	}
+	files, err := getConflictedFiles()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(files, func(f conflictresolver.ConflictedFile) bool {
+		return resolved[f.Path]
+	}), nil
+}
+
+// getConflictedFiles return list of conflict files that parsed from `git status --porcelain=v2 -z`
+func getConflictedFiles() ([]conflictresolver.ConflictedFile, error) {
+	out, err := exec.OutputErr("git", "status", "--porcelain=v2", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("error during get git status: %s: %w", out, err)
+	}
+	return parseConflictedFiles(out), nil
+}
+
+// parseConflictedFiles parses the unmerged entries of `git status --porcelain=v2 -z`,
+// each entry is terminated by NUL and the path is not quoted
 //
-// ### Example output of `git status --short`
+// ### Example entries
 //
-//	UU go.mod
-//	AA go.sum
-//	UU main.go
+//	1 .M N... 100644 100644 100644 3f5a...4b1c 3f5a...4b1c main.go
+//	2 R. N... 100644 100644 100644 3f5a...4b1c 3f5a...4b1c R100 new.go<NUL>old.go
+//	u UU N... 100644 100644 100644 100644 8ab6...9a6d 1f4b...9d2e 5c3e...7a1f go.sum
+//	u UD N... 100644 100644 000000 100644 8ab6...9a6d 1f4b...9d2e 0000...0000 dir/with space.go
 //
 // ### Output notation
 //
-// ref: https://git-scm.com/docs/git-status#_short_format
-func getConflictedFiles() ([]string, error) {
-	out, err := exec.OutputErr("git", "status", "--short")
-	if err != nil {
-		return nil, err
-	}
-	var conflictedFiles []string
-	for _, line := range strings.Split(out, "\n") {
-		content := strings.Split(line, " ")
-		if slices.Contains(gitXYConflictedStatuses, content[0]) {
-			conflictedFiles = append(conflictedFiles, content[1])
+// ref: https://git-scm.com/docs/git-status#_porcelain_format_version_2
+func parseConflictedFiles(out string) []conflictresolver.ConflictedFile {
+	var conflictedFiles []conflictresolver.ConflictedFile
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		switch {
+		case strings.HasPrefix(entry, "2 "):
+			// renamed or copied entry is followed by its original path
+			i++
+		case strings.HasPrefix(entry, "u "):
+			// u <XY> <sub> <m1> <m2> <m3> <mW> <h1> <h2> <h3> <path>
+			fields := strings.SplitN(entry, " ", 11)
+			if len(fields) != 11 || !conflictresolver.IsConflictKind(fields[1]) {
+				slog.Warn("skip invalid unmerged entry", "entry", entry)
+				continue
+			}
+			conflictedFiles = append(conflictedFiles, conflictresolver.ConflictedFile{
+				Path:   fields[10],
+				Kind:   conflictresolver.ConflictKind(fields[1]),
+				Modes:  [3]string{fields[3], fields[4], fields[5]},
+				Hashes: [3]string{fields[7], fields[8], fields[9]},
+			})
 		}
 	}
-	return conflictedFiles, nil
+	return conflictedFiles
 }
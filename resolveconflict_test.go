@@ -1,11 +1,17 @@
 package dx
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/kitimark/dx/pkg/conflictresolver"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/zip"
 )
 
 func TestResolveConflict_GoModConflict(t *testing.T) {
@@ -165,3 +171,1000 @@ func TestResolveConflict_YarnLockConflict(t *testing.T) {
 	yarnlock = tread(t, clientDir+"/yarn.lock")
 	assertNoConflictContent(t, yarnlock)
 }
+
+func TestParseConflictedFiles(t *testing.T) {
+	out := "1 .M N... 100644 100644 100644 3f5a 3f5a main.go\x00" +
+		"2 R. N... 100644 100644 100644 3f5a 3f5a R100 u UU renamed.go\x00old.go\x00" +
+		"u UU N... 100644 100644 100644 100644 8ab6 1f4b 5c3e go.sum\x00" +
+		"u UD N... 100644 100644 000000 100644 8ab6 1f4b 0000 dir/with space.go\x00" +
+		"u DU N... 100644 000000 100644 100644 8ab6 0000 5c3e go.mod\x00" +
+		"u AA N... 000000 100644 100644 100644 0000 1f4b 5c3e yarn.lock\x00" +
+		"u DD N... 100644 000000 000000 000000 8ab6 0000 0000 removed.go\x00" +
+		"u UU S... 160000 160000 160000 160000 9c1d 2e4f 7a3b libs/sub\x00" +
+		"? untracked file\x00"
+
+	actual := parseConflictedFiles(out)
+	assert.Equal(t, []conflictresolver.ConflictedFile{
+		{Path: "go.sum", Kind: conflictresolver.BothModified,
+			Modes: [3]string{"100644", "100644", "100644"}, Hashes: [3]string{"8ab6", "1f4b", "5c3e"}},
+		{Path: "dir/with space.go", Kind: conflictresolver.DeletedByThem,
+			Modes: [3]string{"100644", "100644", "000000"}, Hashes: [3]string{"8ab6", "1f4b", "0000"}},
+		{Path: "go.mod", Kind: conflictresolver.DeletedByUs,
+			Modes: [3]string{"100644", "000000", "100644"}, Hashes: [3]string{"8ab6", "0000", "5c3e"}},
+		{Path: "yarn.lock", Kind: conflictresolver.BothAdded,
+			Modes: [3]string{"000000", "100644", "100644"}, Hashes: [3]string{"0000", "1f4b", "5c3e"}},
+		{Path: "removed.go", Kind: conflictresolver.BothDeleted,
+			Modes: [3]string{"100644", "000000", "000000"}, Hashes: [3]string{"8ab6", "0000", "0000"}},
+		{Path: "libs/sub", Kind: conflictresolver.BothModified,
+			Modes: [3]string{"160000", "160000", "160000"}, Hashes: [3]string{"9c1d", "2e4f", "7a3b"}},
+	}, actual)
+	assert.True(t, actual[5].IsGitlink())
+	assert.False(t, actual[0].IsGitlink())
+}
+
+func TestGetConflictedFiles_DeleteModifyConflict(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	twrite(t, clientDir+"/with space.txt", "base\n")
+	twrite(t, clientDir+"/go.sum", "base\n")
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "base")
+
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	twrite(t, clientDir+"/with space.txt", "feature\n")
+	trun(t, clientDir, "git", "rm", "go.sum")
+	trun(t, clientDir, "git", "commit", "-am", "feature")
+
+	trun(t, clientDir, "git", "checkout", "main")
+	twrite(t, clientDir+"/with space.txt", "main\n")
+	twrite(t, clientDir+"/go.sum", "main\n")
+	trun(t, clientDir, "git", "commit", "-am", "main")
+
+	_, err := trunErr(t, clientDir, "git", "merge", "feature")
+	require.Error(t, err)
+
+	stage := func(n, path string) string {
+		return strings.TrimSpace(trun(t, clientDir, "git", "rev-parse", ":"+n+":"+path))
+	}
+	actual, err := getConflictedFiles()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []conflictresolver.ConflictedFile{
+		{Path: "with space.txt", Kind: conflictresolver.BothModified,
+			Modes:  [3]string{"100644", "100644", "100644"},
+			Hashes: [3]string{stage("1", "with space.txt"), stage("2", "with space.txt"), stage("3", "with space.txt")}},
+		{Path: "go.sum", Kind: conflictresolver.DeletedByThem,
+			Modes:  [3]string{"100644", "100644", "000000"},
+			Hashes: [3]string{stage("1", "go.sum"), stage("2", "go.sum"), strings.Repeat("0", 40)}},
+	}, actual)
+}
+
+func TestResolveConflict_NestedGoModFromSubdirectory(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	moduleDir := clientDir + "/services/api"
+	tmergeConflict(t, clientDir, map[string]string{
+		"services/api/go.mod":  "module test/api\n\ngo 1.21\n",
+		"services/api/main.go": "package main\n\nfunc main() {}\n",
+	}, map[string]string{
+		"services/api/go.mod": "module test/api\n\ngo 1.21\n\nexclude example.com/a v1.0.0\n",
+	}, map[string]string{
+		"services/api/go.mod": "module test/api\n\ngo 1.21\n\nexclude example.com/b v1.0.0\n",
+	})
+
+	tmkdir(t, moduleDir+"/internal")
+	err := os.Chdir(moduleDir + "/internal")
+	require.NoError(t, err)
+	err = trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, moduleDir+"/internal", wd)
+
+	gomod := treadGoMod(t, moduleDir+"/go.mod")
+	var excludes []string
+	for _, e := range gomod.Exclude {
+		excludes = append(excludes, e.Mod.Path)
+	}
+	assert.ElementsMatch(t, []string{"example.com/a", "example.com/b"}, excludes)
+	trun(t, moduleDir, "go", "build", "./...")
+}
+
+func TestResolveConflict_GoModDiff3(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	trun(t, clientDir, "git", "config", "merge.conflictStyle", "diff3")
+
+	tmergeConflict(t, clientDir, map[string]string{
+		"go.mod":  "module test/diff3\n\ngo 1.21\n\nexclude example.com/base v1.0.0\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	}, map[string]string{
+		"go.mod": "module test/diff3\n\ngo 1.21\n\nexclude example.com/a v1.0.0\n",
+	}, map[string]string{
+		"go.mod": "module test/diff3\n\ngo 1.21\n\nexclude example.com/b v1.0.0\n",
+	})
+	require.Contains(t, tread(t, clientDir+"/go.mod"), "|||||||")
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+
+	gomod := treadGoMod(t, clientDir+"/go.mod")
+	var excludes []string
+	for _, e := range gomod.Exclude {
+		excludes = append(excludes, e.Mod.Path)
+	}
+	assert.ElementsMatch(t, []string{"example.com/a", "example.com/b"}, excludes)
+}
+
+func TestResolveConflict_GoModMaxVersion(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	gomod := func(version string) string {
+		return "module test/app\n\ngo 1.21\n\nrequire example.com/lib " + version + "\n\nreplace example.com/lib => ./lib\n"
+	}
+	tmergeConflict(t, clientDir, map[string]string{
+		"lib/go.mod": "module example.com/lib\n\ngo 1.21\n",
+		"lib/lib.go": "package lib\n\nfunc Hello() string { return \"hello\" }\n",
+		"go.mod":     gomod("v1.0.0"),
+		"main.go":    "package main\n\nimport \"example.com/lib\"\n\nfunc main() { println(lib.Hello()) }\n",
+	}, map[string]string{
+		"go.mod": gomod("v1.2.0"),
+	}, map[string]string{
+		"go.mod": gomod("v1.1.0"),
+	})
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	assert.Equal(t, gomod("v1.2.0"), tread(t, clientDir+"/go.mod"))
+	trun(t, clientDir, "go", "build", "./...")
+}
+
+// tpublishModule writes module into file based GOPROXY at proxyDir
+func tpublishModule(t *testing.T, proxyDir, path, version, source string) {
+	t.Helper()
+	srcDir := t.TempDir()
+	gomod := "module " + path + "\n\ngo 1.21\n"
+	twrite(t, srcDir+"/go.mod", gomod)
+	twrite(t, srcDir+"/lib.go", source)
+
+	versionDir := filepath.Join(proxyDir, path, "@v")
+	require.NoError(t, os.MkdirAll(versionDir, 0755))
+	twrite(t, filepath.Join(versionDir, version+".mod"), gomod)
+	twrite(t, filepath.Join(versionDir, version+".info"), `{"Version":"`+version+`"}`)
+	f, err := os.Create(filepath.Join(versionDir, version+".zip"))
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, zip.CreateFromDir(f, module.Version{Path: path, Version: version}, srcDir))
+	list, _ := os.ReadFile(filepath.Join(versionDir, "list"))
+	twrite(t, filepath.Join(versionDir, "list"), string(list)+version+"\n")
+}
+
+func TestResolveConflict_GoSumOffline(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	proxyDir := t.TempDir()
+	tpublishModule(t, proxyDir, "example.com/lib", "v1.0.0", "package lib\n\nfunc Hello() string { return \"hello\" }\n")
+	tpublishModule(t, proxyDir, "example.com/lib", "v1.1.0", "package lib\n\nfunc Hello() string { return \"hi\" }\n")
+	tpublishModule(t, proxyDir, "example.com/extra", "v1.0.0", "package extra\n\nconst Name = \"extra\"\n")
+	t.Setenv("GOMODCACHE", t.TempDir())
+	t.Setenv("GOFLAGS", "-modcacherw")
+	t.Setenv("GOSUMDB", "off")
+	t.Setenv("GOPROXY", "file://"+proxyDir)
+
+	twrite(t, clientDir+"/go.mod", "module test/app\n\ngo 1.21\n\nrequire example.com/lib v1.0.0\n")
+	twrite(t, clientDir+"/main.go", "package main\n\nimport \"example.com/lib\"\n\nfunc main() { println(lib.Hello()) }\n")
+	trun(t, clientDir, "go", "mod", "tidy")
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "init module")
+
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	trun(t, clientDir, "go", "get", "example.com/lib@v1.1.0")
+	trun(t, clientDir, "go", "mod", "tidy")
+	trun(t, clientDir, "git", "commit", "-am", "bump lib")
+
+	trun(t, clientDir, "git", "checkout", "main")
+	twrite(t, clientDir+"/extra.go", "package main\n\nimport \"example.com/extra\"\n\nvar _ = extra.Name\n")
+	trun(t, clientDir, "go", "get", "example.com/extra@v1.0.0")
+	trun(t, clientDir, "go", "mod", "tidy")
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "use extra")
+
+	_, err := trunErr(t, clientDir, "git", "merge", "feature")
+	require.Error(t, err)
+
+	t.Setenv("GOPROXY", "off")
+	err = trunMainCommand(t, "--debug", "resolve-conflict", "--offline")
+	require.NoError(t, err)
+
+	gomod := treadGoMod(t, clientDir+"/go.mod")
+	var requires []string
+	for _, r := range gomod.Require {
+		requires = append(requires, r.Mod.String())
+	}
+	assert.ElementsMatch(t, []string{"example.com/extra@v1.0.0", "example.com/lib@v1.1.0"}, requires)
+	gosum := tread(t, clientDir+"/go.sum")
+	assert.NotContains(t, gosum, "example.com/lib v1.0.0 h1:")
+	assert.Contains(t, gosum, "example.com/lib v1.1.0 h1:")
+	assert.Contains(t, gosum, "example.com/extra v1.0.0 h1:")
+	trun(t, clientDir, "go", "build", "-mod=readonly", "./...")
+}
+
+// tstubCommand puts an executable shell script named name on PATH
+func tstubCommand(t *testing.T, name, script string) {
+	t.Helper()
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, name), []byte("#!/bin/sh\n"+script), 0755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestResolveConflict_PackageLockConflict(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	npmLog := filepath.Join(t.TempDir(), "npm.log")
+	tstubCommand(t, "npm", `echo "$(basename "$PWD") $@" >> `+npmLog+"\n")
+
+	lock := func(lockfileVersion, version string) string {
+		return `{"name": "app", "lockfileVersion": ` + lockfileVersion + `, "packages": {"node_modules/left-pad": {"version": "` + version + `"}}}` + "\n"
+	}
+	tmergeConflict(t, clientDir, map[string]string{
+		"web/package.json":           `{"name": "web"}` + "\n",
+		"web/package-lock.json":      lock("2", "1.0.0"),
+		"legacy/package.json":        `{"name": "legacy"}` + "\n",
+		"legacy/npm-shrinkwrap.json": lock("1", "1.0.0"),
+	}, map[string]string{
+		"web/package-lock.json":      lock("2", "1.2.0"),
+		"legacy/npm-shrinkwrap.json": lock("1", "1.2.0"),
+	}, map[string]string{
+		"web/package-lock.json":      lock("2", "1.1.0"),
+		"legacy/npm-shrinkwrap.json": lock("1", "1.1.0"),
+	})
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	assert.Equal(t, lock("2", "1.1.0"), tread(t, clientDir+"/web/package-lock.json"))
+	assert.Equal(t, lock("1", "1.1.0"), tread(t, clientDir+"/legacy/npm-shrinkwrap.json"))
+	assert.Equal(t, "legacy install --package-lock-only --ignore-scripts --lockfile-version=1\n"+
+		"web install --package-lock-only --ignore-scripts --lockfile-version=2\n", tread(t, npmLog))
+}
+
+func TestResolveConflict_PackageLockWithConflictedPackageJson(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	tstubCommand(t, "npm", "exit 1\n")
+
+	tmergeConflict(t, clientDir, map[string]string{
+		"package.json":      `{"name": "app"}` + "\n",
+		"package-lock.json": `{"lockfileVersion": 3}` + "\n",
+	}, map[string]string{
+		"package.json":      `{"name": "app", "version": "2.0.0"}` + "\n",
+		"package-lock.json": `{"lockfileVersion": 3, "version": "2.0.0"}` + "\n",
+	}, map[string]string{
+		"package.json":      `{"name": "app", "version": "1.1.0"}` + "\n",
+		"package-lock.json": `{"lockfileVersion": 3, "version": "1.1.0"}` + "\n",
+	})
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	assert.ErrorContains(t, err, "package.json file is still conflicted")
+}
+
+func TestResolveConflict_PnpmLockConflict(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	pnpmLog := filepath.Join(t.TempDir(), "pnpm.log")
+	tstubCommand(t, "pnpm", `echo "$(basename "$PWD") $@" >> `+pnpmLog+"\n")
+
+	tmergeConflict(t, clientDir, map[string]string{
+		"pnpm-workspace.yaml":           "packages:\n  - \"packages/*\"\n  - \"!packages/ignored\"\n",
+		"package.json":                  `{"name": "root"}` + "\n",
+		"packages/web/package.json":     `{"name": "web"}` + "\n",
+		"packages/ignored/package.json": `{"name": "ignored"}` + "\n",
+		"pnpm-lock.yaml":                "lockfileVersion: '9.0'\n",
+	}, map[string]string{
+		"packages/web/package.json":     `{"name": "web", "version": "2.0.0"}` + "\n",
+		"packages/ignored/package.json": `{"name": "ignored", "version": "2.0.0"}` + "\n",
+		"pnpm-lock.yaml":                "lockfileVersion: '9.0'\n# feature\n",
+	}, map[string]string{
+		"packages/web/package.json":     `{"name": "web", "version": "1.1.0"}` + "\n",
+		"packages/ignored/package.json": `{"name": "ignored", "version": "1.1.0"}` + "\n",
+		"pnpm-lock.yaml":                "lockfileVersion: '9.0'\n# main\n",
+	})
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.ErrorContains(t, err, "packages/web/package.json")
+	assert.NotContains(t, err.Error(), "packages/ignored/package.json")
+	assert.NoFileExists(t, pnpmLog)
+
+	twrite(t, clientDir+"/packages/web/package.json", `{"name": "web", "version": "2.0.0"}`+"\n")
+	err = trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	assert.Equal(t, "client install --lockfile-only\n", tread(t, pnpmLog))
+}
+
+// tyarnLockConflict commits files then makes yarn.lock conflicted, the lockfile starts with lockHeader
+func tyarnLockConflict(t *testing.T, clientDir string, files map[string]string, lockHeader string) {
+	t.Helper()
+	files["yarn.lock"] = lockHeader + "\n"
+	tmergeConflict(t, clientDir, files,
+		map[string]string{"yarn.lock": lockHeader + "\n# feature\n"},
+		map[string]string{"yarn.lock": lockHeader + "\n# main\n"})
+}
+
+const berryLockHeader = "__metadata:\n  version: 8\n  cacheKey: 10c0"
+
+func TestResolveConflict_YarnBerry(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	yarnLog := filepath.Join(t.TempDir(), "yarn.log")
+	tstubCommand(t, "yarn", `if [ "$1" = "--version" ]; then echo 4.1.0; exit 0; fi
+echo "$@" >> `+yarnLog+"\n")
+
+	tyarnLockConflict(t, clientDir, map[string]string{
+		"package.json": `{"name": "app"}` + "\n",
+		".yarnrc.yml":  "nodeLinker: pnp\n",
+	}, berryLockHeader)
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	assert.Equal(t, "install --mode=update-lockfile\n", tread(t, yarnLog))
+}
+
+func TestResolveConflict_YarnWrongPackageManager(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	tstubCommand(t, "yarn", `if [ "$1" = "--version" ]; then echo 4.1.0; exit 0; fi
+exit 1
+`)
+
+	tyarnLockConflict(t, clientDir, map[string]string{
+		"package.json": `{"name": "app", "packageManager": "pnpm@9.0.0"}` + "\n",
+	}, berryLockHeader)
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	assert.ErrorContains(t, err, "is managed by pnpm 9 (from packageManager of package.json)")
+}
+
+func TestResolveConflict_YarnVersionMismatch(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	tstubCommand(t, "yarn", `if [ "$1" = "--version" ]; then echo 1.22.19; exit 0; fi
+exit 1
+`)
+
+	tyarnLockConflict(t, clientDir, map[string]string{
+		"package.json": `{"name": "app"}` + "\n",
+	}, berryLockHeader)
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	assert.ErrorContains(t, err, "needs yarn 2+ (from header of yarn.lock), but yarn 1.22.19 would run")
+}
+
+func TestResolveConflict_CargoLockConflict(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	cargoLog := filepath.Join(t.TempDir(), "cargo.log")
+	tstubCommand(t, "cargo", `echo "$(basename "$PWD") $@" >> `+cargoLog+"\n")
+
+	crateDir := clientDir + "/services/api"
+	lock := func(version string) string {
+		return "version = 3\n\n[[package]]\nname = \"core\"\nversion = \"" + version + "\"\n"
+	}
+	crates := func(version string) map[string]string {
+		return map[string]string{
+			"services/api/crates/core/Cargo.toml": "[package]\nname = \"core\"\nversion = \"" + version + "\"\n",
+			"libs/shared/Cargo.toml":              "[package]\nname = \"shared\"\nversion = \"" + version + "\"\n",
+			"services/api/Cargo.lock":             lock(version),
+		}
+	}
+	base := crates("0.1.0")
+	base["services/api/Cargo.toml"] = "[workspace]\nmembers = [\n  \"crates/*\",\n  \"../../libs/shared\",\n]\n"
+	tmergeConflict(t, clientDir, base, crates("0.3.0"), crates("0.2.0"))
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.ErrorContains(t, err, "services/api/crates/core/Cargo.toml")
+	assert.NoFileExists(t, cargoLog)
+
+	twrite(t, crateDir+"/crates/core/Cargo.toml", "[package]\nname = \"core\"\nversion = \"0.3.0\"\n")
+	err = trunMainCommand(t, "--debug", "resolve-conflict")
+	// the member outside the workspace directory is checked too
+	require.ErrorContains(t, err, "libs/shared/Cargo.toml")
+	assert.NoFileExists(t, cargoLog)
+
+	twrite(t, clientDir+"/libs/shared/Cargo.toml", "[package]\nname = \"shared\"\nversion = \"0.3.0\"\n")
+	err = trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	assert.Equal(t, lock("0.2.0"), tread(t, crateDir+"/Cargo.lock"))
+
+	err = trunMainCommand(t, "--debug", "resolve-conflict", "--offline")
+	require.NoError(t, err)
+	assert.Equal(t, "api update --workspace\napi update --workspace --offline\n", tread(t, cargoLog))
+}
+
+func TestResolveConflict_PythonLockConflict(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	toolLog := filepath.Join(t.TempDir(), "tool.log")
+	for _, tool := range []string{"poetry", "uv", "pipenv"} {
+		tstubCommand(t, tool, `if [ "$1" = "--version" ]; then echo "Poetry (version 1.8.3)"; exit 0; fi
+echo "$(basename "$PWD") `+tool+` $@" >> `+toolLog+"\n")
+	}
+
+	projects := map[string][2]string{
+		"poetry": {"pyproject.toml", "poetry.lock"},
+		"uv":     {"pyproject.toml", "uv.lock"},
+		"pipenv": {"Pipfile", "Pipfile.lock"},
+	}
+	base, feature, main := map[string]string{}, map[string]string{}, map[string]string{}
+	for dir, p := range projects {
+		base[dir+"/"+p[0]] = "requests = \"2.0\"\n"
+		base[dir+"/"+p[1]] = "hash = \"base\"\n"
+		feature[dir+"/"+p[1]] = "hash = \"feature\"\n"
+		main[dir+"/"+p[1]] = "hash = \"main\"\n"
+	}
+	feature["uv/pyproject.toml"] = "requests = \"2.2\"\n"
+	main["uv/pyproject.toml"] = "requests = \"2.1\"\n"
+	tmergeConflict(t, clientDir, base, feature, main)
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.ErrorContains(t, err, "uv/pyproject.toml file is still conflicted")
+
+	twrite(t, clientDir+"/uv/pyproject.toml", "requests = \"2.2\"\n")
+	err = trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	for dir, p := range projects {
+		assert.Equal(t, "hash = \"main\"\n", tread(t, filepath.Join(clientDir, dir, p[1])))
+	}
+	assert.Equal(t, "poetry poetry lock --no-update\npoetry poetry lock --no-update\nuv uv lock\npipenv pipenv lock\n", tread(t, toolLog))
+}
+
+func TestResolveConflict_GoVendor(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	twrite(t, clientDir+"/go.mod", "module test/app\n\ngo 1.21\n")
+	twrite(t, clientDir+"/main.go", "package main\n\nfunc main() {}\n")
+	trun(t, clientDir, "go", "mod", "vendor")
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "init module")
+
+	addLib := func(name string) {
+		trun(t, clientDir, "mkdir", name)
+		twrite(t, clientDir+"/"+name+"/go.mod", "module example.com/"+name+"\n\ngo 1.21\n")
+		twrite(t, clientDir+"/"+name+"/lib.go", "package "+name+"\n\nconst Name = \""+name+"\"\n")
+		twrite(t, clientDir+"/use_"+name+".go", "package main\n\nimport \"example.com/"+name+"\"\n\nvar _ = "+name+".Name\n")
+		trun(t, clientDir, "go", "mod", "edit", "-require=example.com/"+name+"@v1.0.0", "-replace=example.com/"+name+"=./"+name)
+		trun(t, clientDir, "go", "mod", "vendor")
+		trun(t, clientDir, "git", "add", ".")
+		trun(t, clientDir, "git", "commit", "-m", "use "+name)
+	}
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	addLib("extra")
+	trun(t, clientDir, "git", "checkout", "main")
+	addLib("other")
+
+	_, err := trunErr(t, clientDir, "git", "merge", "feature")
+	require.Error(t, err)
+	require.Contains(t, trun(t, clientDir, "git", "diff", "--name-only", "--diff-filter=U"), "vendor/modules.txt")
+
+	err = trunMainCommand(t, "--debug", "resolve-conflict", "--offline")
+	require.NoError(t, err)
+
+	unmerged := trun(t, clientDir, "git", "diff", "--name-only", "--diff-filter=U")
+	assert.NotContains(t, unmerged, "vendor/")
+	modules := tread(t, clientDir+"/vendor/modules.txt")
+	assert.Contains(t, modules, "# example.com/extra v1.0.0 => ./extra")
+	assert.Contains(t, modules, "# example.com/other v1.0.0 => ./other")
+	trun(t, clientDir, "go", "build", "-mod=vendor", "./...")
+}
+
+func TestResolveConflict_GoWork(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	// -mod=mod is rejected in workspace mode
+	t.Setenv("GOFLAGS", "")
+
+	addModule := func(name string) {
+		trun(t, clientDir, "mkdir", name)
+		twrite(t, clientDir+"/"+name+"/go.mod", "module example.com/"+name+"\n\ngo 1.21\n")
+		twrite(t, clientDir+"/"+name+"/lib.go", "package "+name+"\n")
+	}
+	addModule("a")
+	twrite(t, clientDir+"/go.work", "go 1.21\n\nuse ./a\n")
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "init workspace")
+
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	addModule("b")
+	trun(t, clientDir, "go", "work", "use", "./b")
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "use b")
+
+	trun(t, clientDir, "git", "checkout", "main")
+	addModule("c")
+	trun(t, clientDir, "go", "work", "use", "./c")
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "use c")
+
+	_, err := trunErr(t, clientDir, "git", "merge", "feature")
+	require.Error(t, err)
+
+	err = trunMainCommand(t, "--debug", "resolve-conflict", "--offline")
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(clientDir + "/go.work")
+	require.NoError(t, err)
+	work, err := modfile.ParseWork("go.work", b, nil)
+	require.NoError(t, err)
+	var uses []string
+	for _, u := range work.Use {
+		uses = append(uses, u.Path)
+	}
+	assert.ElementsMatch(t, []string{"./a", "./b", "./c"}, uses)
+	trun(t, clientDir, "go", "build", "./a", "./b", "./c")
+}
+
+func TestResolveConflict_Generate(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	twrite(t, clientDir+"/.dx.yaml", `resolvers:
+  generate:
+    - name: join
+      outputs: ["**/*_gen.txt"]
+      inputs: ["src/*.txt"]
+      command: tr '\n' , < src/names.txt > gen/names_gen.txt
+`)
+	tmergeConflict(t, clientDir, map[string]string{
+		"src/names.txt":     "a\nb\nc\n",
+		"gen/names_gen.txt": "a,b,c,",
+	}, map[string]string{
+		"src/names.txt":     "a\nb\nc\nd\n",
+		"gen/names_gen.txt": "a,b,c,d,",
+	}, map[string]string{
+		"src/names.txt":     "z\na\nb\nc\n",
+		"gen/names_gen.txt": "z,a,b,c,",
+	})
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	assert.Equal(t, "z,a,b,c,d,", tread(t, clientDir+"/gen/names_gen.txt"))
+}
+
+func TestResolveConflict_GenerateInputsConflicted(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	twrite(t, clientDir+"/.dx.yaml", `resolvers:
+  generate:
+    - name: greeting
+      outputs: ["gen/*"]
+      inputs: ["src/*.txt"]
+      command: sed 's/^/hello /' src/names.txt > gen/names.txt
+`)
+	tmergeConflict(t, clientDir, map[string]string{
+		"src/names.txt": "a\n",
+		"gen/names.txt": "hello a\n",
+	}, map[string]string{
+		"src/names.txt": "b\n",
+		"gen/names.txt": "hello b\n",
+	}, map[string]string{
+		"src/names.txt": "c\n",
+		"gen/names.txt": "hello c\n",
+	})
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.ErrorContains(t, err, "inputs of greeting still conflicted")
+	assert.Contains(t, err.Error(), "src/names.txt")
+
+	twrite(t, clientDir+"/src/names.txt", "b\nc\n")
+	err = trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	assert.Equal(t, "hello b\nhello c\n", tread(t, clientDir+"/gen/names.txt"))
+}
+
+func TestResolveConflict_GenerateOutputNotWritten(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	twrite(t, clientDir+"/.dx.yaml", `resolvers:
+  generate:
+    - name: upper
+      outputs: ["gen/*"]
+      command: tr a-z A-Z < src/names.txt > gen/names.txt
+`)
+	tmergeConflict(t, clientDir, map[string]string{
+		"src/names.txt": "a\n",
+		"gen/names.txt": "A\n",
+		"gen/stale.txt": "a\n",
+	}, map[string]string{
+		"src/names.txt": "a\nb\n",
+		"gen/names.txt": "A\nB\n",
+		"gen/stale.txt": "b\n",
+	}, map[string]string{
+		"gen/names.txt": "A\nC\n",
+		"gen/stale.txt": "c\n",
+	})
+
+	// the command doesn't write gen/stale.txt, it must not be resolved to ours
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.ErrorContains(t, err, "generated files still conflicted")
+	assert.Contains(t, err.Error(), "gen/stale.txt")
+	assert.Equal(t, "A\nB\n", tread(t, clientDir+"/gen/names.txt"))
+	assertConflictContent(t, tread(t, clientDir+"/gen/stale.txt"))
+}
+
+func TestResolveConflict_GoImportConflict(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	tmergeConflict(t, clientDir, map[string]string{
+		"go.mod": "module test/app\n\ngo 1.21\n",
+		"main.go": `package main
+
+import (
+	"fmt"
+)
+
+func main() {
+	fmt.Println("hello")
+}
+`,
+	}, map[string]string{
+		"main.go": `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Println("hello")
+}
+
+func exit() {
+	os.Exit(1)
+}
+`,
+	}, map[string]string{
+		"main.go": `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func main() {
+	fmt.Println(strings.ToUpper("hello"))
+}
+`,
+	})
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	assert.Equal(t, `package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	fmt.Println(strings.ToUpper("hello"))
+}
+
+func exit() {
+	os.Exit(1)
+}
+`, tread(t, clientDir+"/main.go"))
+	trun(t, clientDir, "go", "build", "./...")
+}
+
+func TestResolveConflict_Policy(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	twrite(t, clientDir+"/.dx.yaml", `resolvers:
+  policy:
+    - paths: [CHANGELOG.md]
+      policy: union
+    - paths: [CODEOWNERS]
+      policy: sorted-union
+    - paths: ["dist/**"]
+      policy: theirs
+`)
+	tmergeConflict(t, clientDir, map[string]string{
+		"CHANGELOG.md": "# Changelog\n",
+		"CODEOWNERS":   "/api @api\n",
+		"dist/app.js":  "base\n",
+	}, map[string]string{
+		"CHANGELOG.md": "# Changelog\n- add feature\n",
+		"CODEOWNERS":   "/api @api\n/cli @cli\n",
+		"dist/app.js":  "feature\n",
+	}, map[string]string{
+		"CHANGELOG.md": "# Changelog\n- fix bug\n",
+		"CODEOWNERS":   "/api @api\n/web @web\n",
+		"dist/app.js":  "main\n",
+	})
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict", "--offline")
+	require.NoError(t, err)
+	assert.Equal(t, "# Changelog\n- fix bug\n- add feature\n", tread(t, clientDir+"/CHANGELOG.md"))
+	assert.Equal(t, "/api @api\n/cli @cli\n/web @web\n", tread(t, clientDir+"/CODEOWNERS"))
+	assert.Equal(t, "feature\n", tread(t, clientDir+"/dist/app.js"))
+}
+
+func TestResolveConflict_StructuredMerge(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	tmergeConflict(t, clientDir, map[string]string{
+		"locales/en.json": "{\n  \"hello\": \"Hello\"\n}\n",
+		"values.yaml":     "image:\n  tag: v1\n",
+	}, map[string]string{
+		"locales/en.json": "{\n  \"hello\": \"Hello\",\n  \"login\": \"Log in\"\n}\n",
+		"values.yaml":     "image:\n  tag: v2\n",
+	}, map[string]string{
+		"locales/en.json": "{\n  \"hello\": \"Hello\",\n  \"logout\": \"Log out\"\n}\n",
+		"values.yaml":     "image:\n  tag: v3\n",
+	})
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"hello\": \"Hello\",\n  \"login\": \"Log in\",\n  \"logout\": \"Log out\"\n}\n",
+		tread(t, clientDir+"/locales/en.json"))
+	// image.tag is changed on both sides, it's left for human
+	assert.Contains(t, tread(t, clientDir+"/values.yaml"), "<<<<<<<")
+}
+
+func TestResolveConflict_MigrationMerge(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	trun(t, clientDir, "git", "config", "dx.resolvers.migrations", "db/migrations")
+
+	trun(t, clientDir, "mkdir", "-p", "db/migrations")
+	twrite(t, clientDir+"/db/migrations/0001_init.sql", "create table a ();\n")
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "init")
+
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	twrite(t, clientDir+"/db/migrations/0002_orders.up.sql", "create table orders ();\n")
+	twrite(t, clientDir+"/db/migrations/0002_orders.down.sql", "drop table orders;\n")
+	twrite(t, clientDir+"/db/migrations/0003_items.up.sql", "create table items ();\n")
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "add orders and items")
+
+	trun(t, clientDir, "git", "checkout", "main")
+	twrite(t, clientDir+"/db/migrations/0002_users.up.sql", "create table users ();\n")
+	twrite(t, clientDir+"/db/migrations/0002_users.down.sql", "drop table users;\n")
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "add users")
+
+	trun(t, clientDir, "git", "merge", "--no-commit", "feature")
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	files := trun(t, clientDir, "git", "ls-files", "db/migrations")
+	assert.Equal(t, `db/migrations/0001_init.sql
+db/migrations/0002_users.down.sql
+db/migrations/0002_users.up.sql
+db/migrations/0003_orders.down.sql
+db/migrations/0003_orders.up.sql
+db/migrations/0004_items.up.sql
+`, files)
+}
+
+func TestResolveConflict_MigrationAfterRebase(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	twrite(t, clientDir+"/.dx.yaml", "resolvers:\n  migrations: [migrations]\n")
+	trun(t, clientDir, "mkdir", "migrations")
+	twrite(t, clientDir+"/migrations/20240101000000_init.sql", "create table a ();\n")
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "init")
+
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	twrite(t, clientDir+"/migrations/20240301000000_orders.sql", "create table orders ();\n")
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "add orders")
+
+	trun(t, clientDir, "git", "checkout", "main")
+	twrite(t, clientDir+"/migrations/20240401000000_users.sql", "create table users ();\n")
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "add users")
+
+	trun(t, clientDir, "git", "checkout", "feature")
+	trun(t, clientDir, "git", "rebase", "main")
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	files := trun(t, clientDir, "git", "ls-files", "migrations")
+	assert.Equal(t, `migrations/20240101000000_init.sql
+migrations/20240401000000_users.sql
+migrations/20240401000001_orders.sql
+`, files)
+}
+
+// tsubmoduleRepo creates a repository with linear commits of the messages, it returns
+// the repository and hashes of the commits
+func tsubmoduleRepo(t *testing.T, messages ...string) (string, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	trun(t, dir, "git", "init", "-b", "main")
+	trun(t, dir, "git", "config", "--local", "user.name", "tester")
+	trun(t, dir, "git", "config", "--local", "user.email", "tester@example.com")
+	var hashes []string
+	for _, m := range messages {
+		twrite(t, dir+"/file", m+"\n")
+		trun(t, dir, "git", "add", ".")
+		trun(t, dir, "git", "commit", "-m", m)
+		hashes = append(hashes, strings.TrimSpace(trun(t, dir, "git", "rev-parse", "HEAD")))
+	}
+	return dir, hashes
+}
+
+func tcheckoutSubmodule(t *testing.T, clientDir, hash string) {
+	t.Helper()
+	trun(t, clientDir+"/sub", "git", "checkout", "--quiet", hash)
+	trun(t, clientDir, "git", "add", "sub")
+}
+
+func TestResolveConflict_SubmoduleFastForward(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	subDir, c := tsubmoduleRepo(t, "c1", "c2", "c3")
+
+	trun(t, clientDir, "git", "-c", "protocol.file.allow=always", "submodule", "add", subDir, "sub")
+	tcheckoutSubmodule(t, clientDir, c[0])
+	trun(t, clientDir, "git", "commit", "-m", "add submodule")
+
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	tcheckoutSubmodule(t, clientDir, c[1])
+	trun(t, clientDir, "git", "commit", "-m", "update submodule to c2")
+	tcheckoutSubmodule(t, clientDir, c[2])
+	trun(t, clientDir, "git", "commit", "-m", "update submodule to c3")
+
+	// c2 is the base of the cherry-pick, it's not an ancestor of c1
+	trun(t, clientDir, "git", "checkout", "main")
+	trun(t, clientDir+"/sub", "git", "checkout", "--quiet", c[0])
+	_, err := trunErr(t, clientDir, "git", "cherry-pick", "feature")
+	require.Error(t, err)
+
+	err = trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	assert.Equal(t, "160000 "+c[2]+" 0\tsub\n", trun(t, clientDir, "git", "ls-files", "--stage", "sub"))
+	assert.Equal(t, c[0], strings.TrimSpace(trun(t, clientDir+"/sub", "git", "rev-parse", "HEAD")),
+		"submodule worktree must not be changed")
+}
+
+func TestResolveConflict_SubmoduleDiverged(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	subDir, c := tsubmoduleRepo(t, "c1", "c2")
+	trun(t, subDir, "git", "checkout", "-b", "other", c[0])
+	twrite(t, subDir+"/file", "c3\n")
+	trun(t, subDir, "git", "commit", "-am", "c3")
+	c3 := strings.TrimSpace(trun(t, subDir, "git", "rev-parse", "HEAD"))
+
+	trun(t, clientDir, "git", "-c", "protocol.file.allow=always", "submodule", "add", subDir, "sub")
+	tcheckoutSubmodule(t, clientDir, c[0])
+	trun(t, clientDir, "git", "commit", "-m", "add submodule")
+
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	tcheckoutSubmodule(t, clientDir, c[1])
+	trun(t, clientDir, "git", "commit", "-m", "update submodule to c2")
+
+	trun(t, clientDir, "git", "checkout", "main")
+	tcheckoutSubmodule(t, clientDir, c3)
+	trun(t, clientDir, "git", "commit", "-m", "update submodule to c3")
+
+	_, err := trunErr(t, clientDir, "git", "merge", "feature")
+	require.Error(t, err)
+
+	err = trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	stages := trun(t, clientDir, "git", "ls-files", "--stage", "sub")
+	assert.Contains(t, stages, c[1]+" 3\tsub")
+	assert.Contains(t, stages, c3+" 2\tsub")
+}
+
+func TestResolveConflict_Plugin(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	pluginLog := filepath.Join(t.TempDir(), "plugin.log")
+	tstubCommand(t, "dx-resolver-bazel", "echo bazel $1 >> "+pluginLog+"\necho '{}'\n")
+
+	trun(t, clientDir, "mkdir", "-p", "infra", ".dx/resolvers")
+	require.NoError(t, os.WriteFile(clientDir+"/.dx/resolvers/dx-resolver-terraform", []byte(`#!/bin/sh
+echo "terraform $1 $(cat)" >> `+pluginLog+`
+if [ "$1" = resolve ]; then
+  echo 'provider "aws" {}' > infra/.terraform.lock.hcl
+fi
+echo '{"detect": true}'
+`), 0755))
+	twrite(t, clientDir+"/.dx.yaml", "resolvers:\n  order: [terraform]\n  disabled: [json yaml]\n  plugins: [terraform, feature]\n")
+	twrite(t, clientDir+"/infra/.terraform.lock.hcl", "base\n")
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "init")
+
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	twrite(t, clientDir+"/infra/.terraform.lock.hcl", "feature\n")
+	// the plugin of the merged branch is only in the working tree, it must not run
+	require.NoError(t, os.WriteFile(clientDir+"/.dx/resolvers/dx-resolver-feature", []byte(`#!/bin/sh
+echo feature $1 >> `+pluginLog+`
+echo '{"detect": true}'
+`), 0755))
+	trun(t, clientDir, "git", "add", ".")
+	trun(t, clientDir, "git", "commit", "-m", "feature")
+
+	trun(t, clientDir, "git", "checkout", "main")
+	twrite(t, clientDir+"/infra/.terraform.lock.hcl", "main\n")
+	trun(t, clientDir, "git", "commit", "-am", "main")
+
+	_, err := trunErr(t, clientDir, "git", "merge", "feature")
+	require.Error(t, err)
+	require.FileExists(t, clientDir+"/.dx/resolvers/dx-resolver-feature")
+
+	err = trunMainCommand(t, "--debug", "resolve-conflict", "--offline")
+	require.NoError(t, err)
+	assert.Equal(t, "provider \"aws\" {}\n", tread(t, clientDir+"/infra/.terraform.lock.hcl"))
+
+	hash := func(stage string) string {
+		return strings.TrimSpace(trun(t, clientDir, "git", "rev-parse", stage+":infra/.terraform.lock.hcl"))
+	}
+	request := func(action string) string {
+		return `{"version":1,"action":"` + action + `","offline":true,"files":[{"path":"infra/.terraform.lock.hcl","kind":"UU",` +
+			`"modes":["100644","100644","100644"],"hashes":["` + hash(":1") + `","` + hash(":2") + `","` + hash(":3") + `"]}]}`
+	}
+	// the repository plugin runs first by resolvers.order, the PATH plugin only detects
+	assert.Equal(t, "terraform detect "+request("detect")+"\n"+
+		"terraform resolve "+request("resolve")+"\n"+
+		"bazel detect\n", tread(t, pluginLog))
+}
+
+func TestResolveConflict_PluginError(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	tstubCommand(t, "dx-resolver-terraform", `if [ "$1" = detect ]; then
+  echo '{"detect": true}'
+else
+  echo '{"error": "provider hashes cannot be updated"}'
+fi
+`)
+
+	tmergeConflict(t, clientDir,
+		map[string]string{"main.tf": "base\n"},
+		map[string]string{"main.tf": "feature\n"},
+		map[string]string{"main.tf": "main\n"})
+
+	err := trunMainCommand(t, "resolve-conflict")
+	assert.ErrorContains(t, err, "resolver plugin terraform: provider hashes cannot be updated")
+
+	twrite(t, clientDir+"/.dx.yaml", "resolvers:\n  disabled: [terraform]\n")
+	err = trunMainCommand(t, "resolve-conflict")
+	require.NoError(t, err)
+}
+
+func TestResolveConflict_SkipResolvedFiles(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	pluginLog := filepath.Join(t.TempDir(), "plugin.log")
+	tstubCommand(t, "dx-resolver-notes", `[ "$1" = detect ] && grep -o '"path":"[^"]*"' >> `+pluginLog+"\necho '{}'\n")
+
+	tmergeConflict(t, clientDir, map[string]string{
+		".dx.yaml":     "resolvers:\n  policy:\n    - paths: [CHANGELOG.md]\n      policy: ours\n",
+		"CHANGELOG.md": "base\n",
+		"NOTES.md":     "base\n",
+	}, map[string]string{
+		"CHANGELOG.md": "feature\n",
+		"NOTES.md":     "feature\n",
+	}, map[string]string{
+		"CHANGELOG.md": "main\n",
+		"NOTES.md":     "main\n",
+	})
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict")
+	require.NoError(t, err)
+	assert.Equal(t, "main\n", tread(t, clientDir+"/CHANGELOG.md"))
+	// CHANGELOG.md is resolved by the policy, the plugin only sees NOTES.md
+	assert.Equal(t, "\"path\":\"NOTES.md\"\n", tread(t, pluginLog))
+}
+
+func TestResolveConflict_OfflineSkipNetworkResolvers(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	npmLog := filepath.Join(t.TempDir(), "npm.log")
+	tstubCommand(t, "npm", `echo "$@" >> `+npmLog+"\n")
+
+	tmergeConflict(t, clientDir, map[string]string{
+		"package.json":      `{"name": "app"}` + "\n",
+		"package-lock.json": `{"lockfileVersion": 3, "version": "1.0.0"}` + "\n",
+		"CHANGELOG.md":      "base\n",
+		".dx.yaml":          "resolvers:\n  policy:\n    - paths: [CHANGELOG.md]\n      policy: union\n",
+	}, map[string]string{
+		"package-lock.json": `{"lockfileVersion": 3, "version": "2.0.0"}` + "\n",
+		"CHANGELOG.md":      "feature\n",
+	}, map[string]string{
+		"package-lock.json": `{"lockfileVersion": 3, "version": "3.0.0"}` + "\n",
+		"CHANGELOG.md":      "main\n",
+	})
+
+	err := trunMainCommand(t, "--debug", "resolve-conflict", "--offline")
+	require.NoError(t, err)
+	// the policy resolver doesn't need network, npm does
+	assert.Equal(t, "main\nfeature\n", tread(t, clientDir+"/CHANGELOG.md"))
+	assertConflictContent(t, tread(t, clientDir+"/package-lock.json"))
+	assert.NoFileExists(t, npmLog)
+}
@@ -0,0 +1,264 @@
+package dx
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/exec"
+	"github.com/spf13/cobra"
+)
+
+func NewBranchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:  "branch [command]",
+		Args: cobra.NoArgs,
+	}
+
+	createCmd := &cobra.Command{
+		Use:     "create [flags] branch",
+		Example: "branch create feature-b --on feature-a",
+		Args:    cobra.ExactArgs(1),
+		RunE:    journaled(cmdBranchCreateRun),
+	}
+	createCmd.PersistentFlags().String("on", "", "parent branch of the new branch, default is the main branch")
+	cmd.AddCommand(createCmd)
+
+	return cmd
+}
+
+func NewStackCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:  "stack [command]",
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:  "restack",
+		Long: "restack rebases every branch in the stack of current branch onto its parent branch",
+		Args: cobra.NoArgs,
+		RunE: journaled(cmdStackRestackRun),
+	})
+
+	return cmd
+}
+
+func cmdBranchCreateRun(cmd *cobra.Command, args []string) error {
+	parent, err := cmd.Flags().GetString("on")
+	if err != nil {
+		return err
+	}
+	branch := args[0]
+	start := parent
+	if start == "" {
+		start = mainBranchName
+	}
+	out, err := exec.OutputErr("git", "checkout", "-b", branch, start)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("cannot create branch %s: %s: %w", branch, out, err)
+	}
+	if parent == "" {
+		return nil
+	}
+	return setParentBranch(branch, parent)
+}
+
+func cmdStackRestackRun(cmd *cobra.Command, _ []string) error {
+	currentBranch, err := getCurrentBranchName()
+	if err != nil {
+		return err
+	}
+	branches, err := getStackBranches(currentBranch)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	defer func() {
+		out, err := exec.OutputErr("git", "checkout", currentBranch)
+		if err != nil {
+			slog.Warn("cannot checkout branch", "branch", currentBranch, "output", out, "error", err)
+		}
+	}()
+	for _, b := range branches {
+		parent, err := getParentBranch(b)
+		if err != nil {
+			return err
+		}
+		if parent == "" {
+			continue
+		}
+		err = restackBranch(cmd.Context(), b, parent)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getParentBranch returns the parent branch that recorded in
+// `git config branch.<branch>.dxParent`, empty if the branch is based on main branch
+func getParentBranch(branch string) (string, error) {
+	out, err := exec.OutputErr("git", "config", "--get", "branch."+branch+".dxParent")
+	if err != nil {
+		// exit code 1 means the key is not found
+		if out == "" {
+			return "", nil
+		}
+		return "", fmt.Errorf("error during get parent branch: %s: %w", out, err)
+	}
+	return strings.TrimRight(out, "\n"), nil
+}
+
+func setParentBranch(branch, parent string) error {
+	out, err := exec.OutputErr("git", "config", "branch."+branch+".dxParent", parent)
+	if err != nil {
+		return fmt.Errorf("error during set parent branch: %s: %w", out, err)
+	}
+	return nil
+}
+
+// getParentBranches returns map of branch to its parent branch
+//
+// ### Example output of `git config --get-regexp ^branch\..*\.dxparent$`
+//
+//	branch.feature-b.dxparent feature-a
+func getParentBranches() (map[string]string, error) {
+	out, err := exec.OutputErr("git", "config", "--get-regexp", `^branch\..*\.dxparent$`)
+	if err != nil {
+		if out == "" {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("error during get parent branches: %s: %w", out, err)
+	}
+	parents := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		key, parent, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		branch := strings.TrimSuffix(strings.TrimPrefix(key, "branch."), ".dxparent")
+		parents[branch] = parent
+	}
+	return parents, nil
+}
+
+// getStackBranches returns the branches in the stack of branch sorted
+// from the root to the leaves, so the parent always comes before its children
+func getStackBranches(branch string) ([]string, error) {
+	parents, err := getParentBranches()
+	if err != nil {
+		return nil, err
+	}
+	root := branch
+	visited := map[string]bool{root: true}
+	for parents[root] != "" {
+		root = parents[root]
+		if visited[root] {
+			return nil, fmt.Errorf("branch %s has circular parent", root)
+		}
+		visited[root] = true
+	}
+
+	stack := []string{root}
+	for i := 0; i < len(stack); i++ {
+		var children []string
+		for child, parent := range parents {
+			if parent == stack[i] {
+				children = append(children, child)
+			}
+		}
+		slices.Sort(children)
+		stack = append(stack, children...)
+	}
+	return stack, nil
+}
+
+// getChangeIdOwners returns map of change id to the branch that owns it. the change id
+// is owned by the nearest ancestor to the root of the stack that contains it
+func getChangeIdOwners(ctx context.Context, branch string) (map[string]string, error) {
+	chain := []string{branch}
+	for {
+		parent, err := getParentBranch(chain[len(chain)-1])
+		if err != nil {
+			return nil, err
+		}
+		if parent == "" || slices.Contains(chain, parent) {
+			break
+		}
+		chain = append(chain, parent)
+	}
+
+	owners := map[string]string{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		commits, err := getCommitsFromMainToBranchName(ctx, chain[i])
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range commits {
+			for _, id := range c.ChangeIDs {
+				if _, ok := owners[id]; !ok {
+					owners[id] = chain[i]
+				}
+			}
+		}
+	}
+	return owners, nil
+}
+
+// restackBranch replays the commits that owned by branch onto the parent,
+// commits of parent are matched by change id so the amended parent commits are dropped
+func restackBranch(ctx context.Context, branch, parent string) error {
+	commits, err := getCommits(ctx, branch, parent)
+	if err != nil {
+		return err
+	}
+	parentCommits, err := getCommitsFromMainToBranchName(ctx, parent)
+	if err != nil {
+		return err
+	}
+	var parentChangeIds []string
+	for _, c := range parentCommits {
+		parentChangeIds = append(parentChangeIds, c.ChangeIDs...)
+	}
+	var ownCommits []*Commit
+	for _, c := range commits {
+		if len(c.ChangeIDs) != 0 && slices.Contains(parentChangeIds, c.ChangeIDs[0]) {
+			continue
+		}
+		ownCommits = append(ownCommits, c)
+	}
+
+	_, err = exec.OutputErrContext(ctx, "git", "merge-base", "--is-ancestor", parent, branch)
+	if err == nil && len(ownCommits) == len(commits) {
+		slog.Info("branch is up to date", "branch", branch, "parent", parent)
+		return nil
+	}
+
+	slog.Info("restack branch", "branch", branch, "parent", parent, "commits", len(ownCommits))
+	out, err := exec.OutputErrContext(ctx, "git", "checkout", "--detach", parent)
+	if err != nil {
+		return fmt.Errorf("cannot checkout %s: %s: %w", parent, out, err)
+	}
+	for i := len(ownCommits) - 1; i >= 0; i-- {
+		out, err = exec.OutputErrContext(ctx, "git", "cherry-pick", "--allow-empty", ownCommits[i].Hash)
+		if err != nil {
+			abortOut, abortErr := exec.OutputErr("git", "cherry-pick", "--abort")
+			if abortErr != nil {
+				slog.Warn("cannot abort cherry-pick", "output", abortOut, "error", abortErr)
+			}
+			if isCodeConflict(out) {
+				return fmt.Errorf("code conflict during restack %s onto %s, rebase it manually", branch, parent)
+			}
+			return fmt.Errorf("cannot restack %s onto %s: %s: %w", branch, parent, out, err)
+		}
+	}
+	out, err = exec.OutputErrContext(ctx, "git", "checkout", "-B", branch)
+	if err != nil {
+		return fmt.Errorf("cannot update branch %s: %s: %w", branch, out, err)
+	}
+	return nil
+}
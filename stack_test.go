@@ -0,0 +1,142 @@
+package dx
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStack_SyncAttributeChangeIdToOwner(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	t.Log("develop feature-a")
+	err := trunMainCommand(t, "branch", "create", "feature-a")
+	require.NoError(t, err)
+	twrite(t, clientDir+"/a", "feature a\n")
+	trun(t, clientDir, "git", "add", "a")
+	err = trunMainCommand(t, "commit", "-m", "feat: feature a")
+	require.NoError(t, err)
+
+	t.Log("develop feature-b on feature-a")
+	err = trunMainCommand(t, "branch", "create", "feature-b", "--on", "feature-a")
+	require.NoError(t, err)
+	assert.Equal(t, "feature-b", tgetHeadBranch(t, clientDir))
+	parent, err := getParentBranch("feature-b")
+	require.NoError(t, err)
+	assert.Equal(t, "feature-a", parent)
+	twrite(t, clientDir+"/b", "feature b\n")
+	trun(t, clientDir, "git", "add", "b")
+	err = trunMainCommand(t, "commit", "-m", "feat: feature b")
+	require.NoError(t, err)
+
+	err = trunMainCommand(t, "sync", "dev")
+	require.NoError(t, err)
+	tgitLog(t, clientDir, "dev")
+	actualCommits := tgetCommits(t, clientDir, "dev")
+	assert.Equal(t, "sync from feature-b", actualCommits[0].short)
+	require.Len(t, actualCommits[0].subCommit, 1)
+	assert.Equal(t, "feat: feature b", actualCommits[0].subCommit[0].short)
+	assert.Equal(t, "sync from feature-a", actualCommits[1].short)
+	require.Len(t, actualCommits[1].subCommit, 1)
+	assert.Equal(t, "feat: feature a", actualCommits[1].subCommit[0].short)
+	trun(t, clientDir, "git", "checkout", "dev")
+	assert.Equal(t, "feature a\n", tread(t, clientDir+"/a"))
+	assert.Equal(t, "feature b\n", tread(t, clientDir+"/b"))
+	assertNormalTeardown(t, clientDir)
+}
+
+func TestStack_Restack(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	err := trunMainCommand(t, "branch", "create", "feature-a")
+	require.NoError(t, err)
+	twrite(t, clientDir+"/a", "feature a\n")
+	trun(t, clientDir, "git", "add", "a")
+	err = trunMainCommand(t, "commit", "-m", "feat: feature a")
+	require.NoError(t, err)
+
+	err = trunMainCommand(t, "branch", "create", "feature-b", "--on", "feature-a")
+	require.NoError(t, err)
+	twrite(t, clientDir+"/b", "feature b\n")
+	trun(t, clientDir, "git", "add", "b")
+	err = trunMainCommand(t, "commit", "-m", "feat: feature b")
+	require.NoError(t, err)
+
+	err = trunMainCommand(t, "branch", "create", "feature-c", "--on", "feature-b")
+	require.NoError(t, err)
+	twrite(t, clientDir+"/c", "feature c\n")
+	trun(t, clientDir, "git", "add", "c")
+	err = trunMainCommand(t, "commit", "-m", "feat: feature c")
+	require.NoError(t, err)
+
+	t.Log("amend feature-a")
+	trun(t, clientDir, "git", "checkout", "feature-a")
+	twrite(t, clientDir+"/a", "feature a amended\n")
+	trun(t, clientDir, "git", "add", "a")
+	trun(t, clientDir, "git", "commit", "--amend", "--no-edit")
+
+	err = trunMainCommand(t, "stack", "restack")
+	require.NoError(t, err)
+	tgitLog(t, clientDir, "feature-c")
+	assert.Equal(t, "feature-a", tgetHeadBranch(t, clientDir))
+	for _, b := range []string{"feature-b", "feature-c"} {
+		_, err = trunErr(t, clientDir, "git", "merge-base", "--is-ancestor", "feature-a", b)
+		assert.NoError(t, err, "%s must be based on feature-a", b)
+	}
+	commits := tgetCommits(t, clientDir, "main..feature-c")
+	require.Len(t, commits, 3)
+	assert.Equal(t, "feat: feature c", commits[0].short)
+	assert.Equal(t, "feat: feature b", commits[1].short)
+	assert.Equal(t, "feat: feature a", commits[2].short)
+	trun(t, clientDir, "git", "checkout", "feature-c")
+	assert.Equal(t, "feature a amended\n", tread(t, clientDir+"/a"))
+
+	t.Log("restack is undoable")
+	trun(t, clientDir, "git", "checkout", "feature-a")
+	err = trunMainCommand(t, "undo")
+	require.NoError(t, err)
+	_, err = trunErr(t, clientDir, "git", "merge-base", "--is-ancestor", "feature-a", "feature-b")
+	assert.Error(t, err)
+}
+
+func TestStack_SyncRollbackPartialSquash(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	err := trunMainCommand(t, "branch", "create", "feature-a")
+	require.NoError(t, err)
+	twrite(t, clientDir+"/a", "feature a\n")
+	trun(t, clientDir, "git", "add", "a")
+	err = trunMainCommand(t, "commit", "-m", "feat: feature a")
+	require.NoError(t, err)
+
+	err = trunMainCommand(t, "branch", "create", "feature-b", "--on", "feature-a")
+	require.NoError(t, err)
+	twrite(t, clientDir+"/b", "feature b\n")
+	trun(t, clientDir, "git", "add", "b")
+	err = trunMainCommand(t, "commit", "-m", "feat: feature b")
+	require.NoError(t, err)
+
+	t.Log("commit of second squashed group is failed")
+	twrite(t, clientDir+"/.git/hooks/commit-msg", "#!/bin/sh\n! grep -q 'sync from feature-b' \"$1\"\n")
+	trun(t, clientDir, "chmod", "+x", ".git/hooks/commit-msg")
+	devHead := trun(t, clientDir, "git", "rev-parse", "dev")
+	featureHead := trun(t, clientDir, "git", "rev-parse", "feature-b")
+
+	err = trunMainCommand(t, "sync", "dev")
+	require.Error(t, err)
+	assert.Equal(t, devHead, trun(t, clientDir, "git", "rev-parse", "dev"))
+	assert.Equal(t, featureHead, trun(t, clientDir, "git", "rev-parse", "feature-b"))
+	assert.Equal(t, "feature-b", tgetHeadBranch(t, clientDir))
+	assert.Empty(t, trun(t, clientDir, "git", "status", "--porcelain"))
+	assertNormalTeardown(t, clientDir)
+
+	require.NoError(t, os.Remove(clientDir+"/.git/hooks/commit-msg"))
+	err = trunMainCommand(t, "sync", "dev")
+	require.NoError(t, err)
+	actualCommits := tgetCommits(t, clientDir, "dev")
+	assert.Equal(t, "sync from feature-b", actualCommits[0].short)
+	assert.Equal(t, "sync from feature-a", actualCommits[1].short)
+	assertNormalTeardown(t, clientDir)
+}
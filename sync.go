@@ -1,12 +1,18 @@
 package dx
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"os/signal"
 	"slices"
 	"strings"
+	"syscall"
 
+	"github.com/kitimark/dx/pkg/config"
+	"github.com/kitimark/dx/pkg/conflictresolver"
 	"github.com/kitimark/dx/pkg/exec"
 	"github.com/spf13/cobra"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -15,8 +21,9 @@ import (
 func NewSyncCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:  "sync [flags] [--continue | branch]",
+		Long: "sync squashes the pending commits of current branch into the sync branch, the branch can be omitted when sync.targets config has only one target",
 		Args: cmdSyncArgs,
-		RunE: cmdSyncRun,
+		RunE: journaled(cmdSyncRun),
 	}
 
 	cmd.PersistentFlags().Bool("continue", false, "continue sync commits")
@@ -35,7 +42,7 @@ func cmdSyncArgs(cmd *cobra.Command, args []string) error {
 			return errors.New("no required arguments")
 		}
 	} else {
-		if len(args) != 1 {
+		if len(args) > 1 {
 			return errors.New("require only one arguments")
 		}
 	}
@@ -43,16 +50,28 @@ func cmdSyncArgs(cmd *cobra.Command, args []string) error {
 }
 
 func cmdSyncRun(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := runSync(ctx, cmd, args)
+	if err != nil && ctx.Err() != nil {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("sync is interrupted: %w", err)
+	}
+	return err
+}
+
+func runSync(ctx context.Context, cmd *cobra.Command, args []string) error {
 	var s *sync
 	flags := cmd.Flags()
 	con, err := flags.GetBool("continue")
 	if con {
-		s, err = prepareContinueSync()
+		s, err = prepareContinueSync(ctx)
 		if err != nil {
 			return err
 		}
 	} else {
-		s, err = prepareSync(args)
+		s, err = prepareSync(ctx, args)
 		if err != nil {
 			s.cleanup()
 			return err
@@ -81,7 +100,7 @@ func cmdSyncRun(cmd *cobra.Command, args []string) error {
 	slog.Info("pending commit", "first", pendingCommitIndex, "last", 0)
 
 	for i := pendingCommitIndex; i >= 0; i-- {
-		out, err := exec.OutputErr("git", "cherry-pick", s.currentCommits[i].Hash)
+		out, err := exec.OutputErrContext(ctx, "git", "cherry-pick", s.currentCommits[i].Hash)
 		if err != nil {
 			if isCodeConflict(out) {
 				fmt.Printf(`CONFLICT: syncing commit to %s
@@ -91,6 +110,7 @@ hint: "dx sync --continue"
 `, s.syncBranch)
 				s.tdOpts.ignoreSwitchBranchBack = true
 				s.tmpSyncBranch.ignoreCleanup = true
+				s.lock.pause()
 				cmd.SilenceUsage = true
 				return errors.New("code conflict")
 			}
@@ -98,31 +118,98 @@ hint: "dx sync --continue"
 		}
 	}
 
-	_, err = exec.OutputErr("git", "checkout", s.syncBranch)
+	err = renumberMigrations(ctx, s.syncBranch)
 	if err != nil {
 		return err
 	}
-	_, err = exec.OutputErr("git", "merge", "--squash", s.tmpSyncBranch.name)
+
+	err = runVerifyCommands(ctx)
 	if err != nil {
+		cmd.SilenceUsage = true
 		return err
 	}
-	commitLogs := "#commits\n"
-	for i := len(s.tmpSyncedCommits) - 1; i >= 0; i-- {
-		commitLogs += s.tmpSyncedCommits[i].Message
-		commitLogs += "---\n"
+
+	_, err = exec.OutputErrContext(ctx, "git", "checkout", s.syncBranch)
+	if err != nil {
+		return err
 	}
-	for i := pendingCommitIndex; i >= 0; i-- {
-		commitLogs += s.currentCommits[i].Message
-		commitLogs += "---\n"
+	if cfg.Sync.Strategy == config.StrategyCherryPick {
+		_, err = exec.OutputErrContext(ctx, "git", "merge", "--ff-only", s.tmpSyncBranch.name)
+		return err
 	}
-	_, err = exec.OutputErr("git", "commit", "-m", "sync from "+s.currentBranch, "-m", commitLogs)
+	return s.squash(ctx)
+}
+
+// syncGroup is the synced commits that owned by the same branch
+type syncGroup struct {
+	owner string
+	// commits are sorted by create time asc
+	commits []*Commit
+}
+
+// squash commits the synced commits into the sync branch, one squashed
+// commit for each branch in the stack that owns the commits
+func (s *sync) squash(ctx context.Context) error {
+	tmpSyncedCommits, err := getCommits(ctx, s.tmpSyncBranch.name, s.syncBranch)
 	if err != nil {
 		return err
 	}
-
+	owners, err := getChangeIdOwners(ctx, s.currentBranch)
+	if err != nil {
+		return err
+	}
+	head, err := exec.OutputErrContext(ctx, "git", "rev-parse", "HEAD")
+	if err != nil {
+		return fmt.Errorf("cannot get head of %s: %s: %w", s.syncBranch, head, err)
+	}
+	// the groups are committed one by one, rollback resets the sync branch to head
+	// so a partial set of squashed commits isn't left
+	s.squashFrom = strings.TrimSpace(head)
+	for _, g := range groupCommitsByOwner(tmpSyncedCommits, owners, s.currentBranch) {
+		_, err = exec.OutputErrContext(ctx, "git", "merge", "--squash", g.commits[len(g.commits)-1].Hash)
+		if err != nil {
+			return err
+		}
+		commitLogs := "#commits\n"
+		for _, c := range g.commits {
+			commitLogs += c.Message
+			commitLogs += "---\n"
+		}
+		_, err = exec.OutputErrContext(ctx, "git", "commit", "-m", "sync from "+g.owner, "-m", commitLogs)
+		if err != nil {
+			return err
+		}
+	}
+	s.squashFrom = ""
 	return nil
 }
 
+// groupCommitsByOwner groups the consecutive commits that owned by the same branch,
+// commits are sorted by create time desc as `git log` and the commit without known
+// owner belongs to the group before it
+func groupCommitsByOwner(commits []*Commit, owners map[string]string, defaultOwner string) []*syncGroup {
+	var groups []*syncGroup
+	for i := len(commits) - 1; i >= 0; i-- {
+		c := commits[i]
+		owner := ""
+		if len(c.ChangeIDs) != 0 {
+			owner = owners[c.ChangeIDs[0]]
+		}
+		if owner == "" {
+			owner = defaultOwner
+			if len(groups) != 0 {
+				owner = groups[len(groups)-1].owner
+			}
+		}
+		if len(groups) == 0 || groups[len(groups)-1].owner != owner {
+			groups = append(groups, &syncGroup{owner: owner})
+		}
+		g := groups[len(groups)-1]
+		g.commits = append(g.commits, c)
+	}
+	return groups
+}
+
 type sync struct {
 	syncBranch    string
 	syncedCommits []*Commit
@@ -130,8 +217,12 @@ type sync struct {
 	currentBranch  string
 	currentCommits []*Commit
 
-	tmpSyncBranch    *tmpSyncBranch
-	tmpSyncedCommits []*Commit
+	tmpSyncBranch *tmpSyncBranch
+
+	lock *syncLock
+	// squashFrom is the head of sync branch before squash, it's set
+	// until every squashed commit is committed into the sync branch
+	squashFrom string
 
 	tdOpts     *teardownOpts
 	cleanupFns []func()
@@ -141,31 +232,73 @@ func (s *sync) registerCleanup(fn func()) {
 	s.cleanupFns = append(s.cleanupFns, fn)
 }
 
+// cleanup runs the registered cleanup functions in reverse order,
+// it must not use the sync context because the context might be
+// already canceled by a signal.
 func (s *sync) cleanup() {
 	for i := len(s.cleanupFns) - 1; i >= 0; i-- {
 		s.cleanupFns[i]()
 	}
 }
 
-func prepareSync(args []string) (s *sync, err error) {
+// registerTeardown registers the cleanup functions that run in order
+// rollback, switch back to current branch, remove temp branch and release lock
+func (s *sync) registerTeardown() {
+	s.registerCleanup(s.lock.release)
+	s.registerCleanup(s.tmpSyncBranch.cleanup)
+	s.registerCleanup(s.switchBranchBack)
+	s.registerCleanup(s.rollback)
+}
+
+func (s *sync) switchBranchBack() {
+	if s.tdOpts.ignoreSwitchBranchBack {
+		return
+	}
+	out, err := exec.OutputErr("git", "checkout", s.currentBranch)
+	if err != nil {
+		slog.Warn("cannot checkout branch", "branch", s.currentBranch, "output", out, "error", err)
+	}
+}
+
+// rollback reverts the partial state that left by failed or interrupted sync,
+// sync that paused on code conflict is kept for `dx sync --continue`
+func (s *sync) rollback() {
+	if s.tdOpts.ignoreSwitchBranchBack {
+		return
+	}
+	cherryPicking, err := isCherryPickInProgress(".")
+	if err != nil {
+		slog.Warn("cannot check cherry-pick state", "error", err)
+	}
+	if cherryPicking {
+		slog.Info("abort unfinished cherry-pick")
+		out, err := exec.OutputErr("git", "cherry-pick", "--abort")
+		if err != nil {
+			slog.Warn("cannot abort cherry-pick", "output", out, "error", err)
+		}
+	}
+	if s.squashFrom != "" {
+		slog.Info("discard unfinished squash", "branch", s.syncBranch, "head", s.squashFrom)
+		out, err := exec.OutputErr("git", "reset", "--hard", s.squashFrom)
+		if err != nil {
+			slog.Warn("cannot discard squash changes", "output", out, "error", err)
+		}
+	}
+}
+
+func prepareSync(ctx context.Context, args []string) (s *sync, err error) {
 	s = &sync{
 		tdOpts: &teardownOpts{},
 	}
 
-	s.syncBranch = args[0]
+	s.syncBranch, err = getSyncTarget(args)
+	if err != nil {
+		return
+	}
 	s.currentBranch, err = getCurrentBranchName()
 	if err != nil {
 		return
 	}
-	s.registerCleanup(func() {
-		if s.tdOpts.ignoreSwitchBranchBack {
-			return
-		}
-		out, err := exec.OutputErr("git", "checkout", s.currentBranch)
-		if err != nil {
-			slog.Warn("cannot checkout branch", "branch", s.currentBranch, "output", out, "error", err)
-		}
-	})
 
 	if s.currentBranch == s.syncBranch {
 		slog.Error("cannot sync branch with same branch", "current_branch", s.currentBranch,
@@ -174,30 +307,33 @@ func prepareSync(args []string) (s *sync, err error) {
 		return
 	}
 
-	err = resetBranchFromOrigin(s.syncBranch)
+	s.tmpSyncBranch = newTempSyncBranch(s.currentBranch, s.syncBranch)
+	s.lock, err = acquireSyncLock(s.syncBranch, s.currentBranch, s.tmpSyncBranch.name)
 	if err != nil {
 		return
 	}
+	s.registerTeardown()
 
-	slog.Info("syncing branch", "branch_to", s.syncBranch, "branch_from", s.currentBranch)
-	s.currentCommits, err = getCommitsFromMainToBranchName(s.currentBranch)
+	err = resetBranchFromOrigin(ctx, s.syncBranch)
 	if err != nil {
 		return
 	}
-	s.syncedCommits, err = getCommitsFromMainToBranchName(s.syncBranch)
+
+	slog.Info("syncing branch", "branch_to", s.syncBranch, "branch_from", s.currentBranch)
+	s.currentCommits, err = getCommitsFromMainToBranchName(ctx, s.currentBranch)
 	if err != nil {
 		return
 	}
-
-	s.tmpSyncBranch, err = newTempSyncBranch(s.currentBranch, s.syncBranch)
+	s.syncedCommits, err = getCommitsFromMainToBranchName(ctx, s.syncBranch)
 	if err != nil {
 		return
 	}
-	s.registerCleanup(s.tmpSyncBranch.cleanup)
+
+	err = s.tmpSyncBranch.create(ctx)
 	return
 }
 
-func prepareContinueSync() (s *sync, err error) {
+func prepareContinueSync(ctx context.Context) (s *sync, err error) {
 	tmpSyncBranchName, err := getCurrentBranchName()
 	if err != nil {
 		return
@@ -212,36 +348,87 @@ func prepareContinueSync() (s *sync, err error) {
 		tdOpts:        &teardownOpts{},
 		tmpSyncBranch: tmpSyncBranch,
 	}
-	s.registerCleanup(func() {
-		if s.tdOpts.ignoreSwitchBranchBack {
-			return
-		}
-		out, err := exec.OutputErr("git", "checkout", s.currentBranch)
+
+	s.lock, err = resumeSyncLock(s.syncBranch, s.currentBranch, s.tmpSyncBranch.name)
+	if err != nil {
+		return
+	}
+	defer func() {
+		// keep the sync paused, so it can be continued again
 		if err != nil {
-			slog.Warn("cannot checkout branch", "branch", s.currentBranch, "output", out, "error", err)
+			s.lock.pause()
 		}
-	})
+	}()
 
 	slog.Info("continue syncing branch", "branch_to", s.syncBranch, "branch_from", s.currentBranch)
-	_, err = exec.OutputErr("git", "-c", "core.editor=true", "cherry-pick", "--continue")
+	_, err = exec.OutputErrContext(ctx, "git", "-c", "core.editor=true", "cherry-pick", "--continue")
 	if err != nil {
 		return
 	}
-	s.currentCommits, err = getCommitsFromMainToBranchName(s.currentBranch)
+	s.currentCommits, err = getCommitsFromMainToBranchName(ctx, s.currentBranch)
 	if err != nil {
 		return
 	}
-	s.syncedCommits, err = getCommitsFromMainToBranchName(s.tmpSyncBranch.name)
+	s.syncedCommits, err = getCommitsFromMainToBranchName(ctx, s.tmpSyncBranch.name)
 	if err != nil {
 		return
 	}
-	s.tmpSyncedCommits, err = getCommits(s.tmpSyncBranch.name, s.syncBranch)
+
+	s.registerTeardown()
+	return
+}
+
+// getSyncTarget returns the sync branch from args, or from sync.targets config
+// when it has only one target
+func getSyncTarget(args []string) (string, error) {
+	targets := cfg.Sync.Targets
+	if len(args) == 0 {
+		if len(targets) != 1 {
+			return "", errors.New("require sync branch argument")
+		}
+		return targets[0], nil
+	}
+	if len(targets) != 0 && !slices.Contains(targets, args[0]) {
+		return "", fmt.Errorf("%s is not in sync.targets (%s) from %s", args[0],
+			strings.Join(targets, ", "), cfg.Source("sync.targets"))
+	}
+	return args[0], nil
+}
+
+// renumberMigrations renumbers the synced migrations that collide with the migrations
+// of the sync branch, the renames are committed on top of the synced commits
+func renumberMigrations(ctx context.Context, syncBranch string) error {
+	if len(cfg.Resolvers.Migrations) == 0 {
+		return nil
+	}
+	root, err := getRepoRoot()
 	if err != nil {
-		return
+		return err
+	}
+	renamed, err := conflictresolver.RenumberMigrations(root, cfg.Resolvers.Migrations, syncBranch)
+	if err != nil {
+		return err
+	}
+	if renamed == 0 {
+		return nil
+	}
+	out, err := exec.OutputErrContext(ctx, "git", "commit", "-m", "renumber migrations after "+syncBranch)
+	if err != nil {
+		return fmt.Errorf("cannot commit renumbered migrations: %s: %w", out, err)
 	}
+	return nil
+}
 
-	s.registerCleanup(s.tmpSyncBranch.cleanup)
-	return
+// runVerifyCommands runs sync.verify commands on the synced commits
+func runVerifyCommands(ctx context.Context) error {
+	for _, c := range cfg.Sync.Verify {
+		slog.Info("verify synced commits", "command", c)
+		out, err := exec.OutputErrContext(ctx, "sh", "-c", c)
+		if err != nil {
+			return fmt.Errorf("verify command %q failed: %s: %w", c, out, err)
+		}
+	}
+	return nil
 }
 
 func getCurrentBranchName() (string, error) {
@@ -252,41 +439,41 @@ func getCurrentBranchName() (string, error) {
 	return strings.TrimRight(currentBranchName, "\n"), nil
 }
 
-func resetBranchFromOrigin(syncBranch string) error {
+func resetBranchFromOrigin(ctx context.Context, syncBranch string) error {
 	slog.Info("try to reset the sync branch", "branch", syncBranch)
 	currentBranch, err := getCurrentBranchName()
 	if err != nil {
 		return nil
 	}
-	_, err = exec.OutputErr("git", "fetch")
+	_, err = exec.OutputErrContext(ctx, "git", "fetch", cfg.Remote)
 	if err != nil {
 		return err
 	}
-	_, err = exec.OutputErr("git", "checkout", syncBranch)
+	_, err = exec.OutputErrContext(ctx, "git", "checkout", syncBranch)
 	if err != nil {
 		return err
 	}
-	_, err = exec.OutputErr("git", "reset", "--hard", "origin/"+syncBranch)
+	_, err = exec.OutputErrContext(ctx, "git", "reset", "--hard", cfg.Remote+"/"+syncBranch)
 	if err != nil {
 		return err
 	}
-	_, err = exec.OutputErr("git", "checkout", currentBranch)
+	_, err = exec.OutputErrContext(ctx, "git", "checkout", currentBranch)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func getCommitsFromMainToBranchName(branchName string) ([]*Commit, error) {
-	commits, err := getCommits(branchName, mainBranchName)
+func getCommitsFromMainToBranchName(ctx context.Context, branchName string) ([]*Commit, error) {
+	commits, err := getCommits(ctx, branchName, mainBranchName)
 	if err != nil {
 		return nil, err
 	}
 	return commits, nil
 }
 
-func getCommits(headBranch, baseBranch string) ([]*Commit, error) {
-	out, err := exec.OutputErr("git", "log", "--format=format:%H%x00%B%x00",
+func getCommits(ctx context.Context, headBranch, baseBranch string) ([]*Commit, error) {
+	out, err := exec.OutputErrContext(ctx, "git", "log", "--format=format:%H%x00%B%x00",
 		baseBranch+".."+headBranch)
 	if err != nil {
 		return nil, fmt.Errorf("got error during execute: %s: %w", out, err)
@@ -319,27 +506,35 @@ type tmpSyncBranch struct {
 	name          string
 	from          string
 	to            string
+	id            bson.ObjectID
 	ignoreCleanup bool
 }
 
-func newTempSyncBranch(from, to string) (*tmpSyncBranch, error) {
-	b := &tmpSyncBranch{
-		name: fmt.Sprintf("tmp-sync-%s-%s-%s", b32en(to), b32en(from), bson.NewObjectID().Hex()),
+const tmpSyncBranchPrefix = "tmp-sync-"
+
+func newTempSyncBranch(from, to string) *tmpSyncBranch {
+	id := bson.NewObjectID()
+	return &tmpSyncBranch{
+		name: fmt.Sprintf("%s%s-%s-%s", tmpSyncBranchPrefix, b32en(to), b32en(from), id.Hex()),
 		from: from,
 		to:   to,
+		id:   id,
 	}
-	_, err := exec.OutputErr("git", "checkout", "-b", b.name, to)
-	if err != nil {
-		return nil, err
-	}
-	return b, nil
+}
+
+func (b *tmpSyncBranch) create(ctx context.Context) error {
+	_, err := exec.OutputErrContext(ctx, "git", "checkout", "-b", b.name, b.to)
+	return err
 }
 
 func parseTempSyncBranch(tmpBranch string) (*tmpSyncBranch, error) {
-	if !strings.HasPrefix(tmpBranch, "tmp-sync-") {
+	if !strings.HasPrefix(tmpBranch, tmpSyncBranchPrefix) {
+		return nil, errors.New("invalid temp branch")
+	}
+	data := strings.Split(tmpBranch[len(tmpSyncBranchPrefix):], "-")
+	if len(data) != 3 {
 		return nil, errors.New("invalid temp branch")
 	}
-	data := strings.Split(tmpBranch[len("tmp-sync-"):], "-")
 	to, err := b32de(data[0])
 	if err != nil {
 		return nil, err
@@ -348,15 +543,20 @@ func parseTempSyncBranch(tmpBranch string) (*tmpSyncBranch, error) {
 	if err != nil {
 		return nil, err
 	}
+	id, err := bson.ObjectIDFromHex(data[2])
+	if err != nil {
+		return nil, err
+	}
 	return &tmpSyncBranch{
 		name: tmpBranch,
 		from: from,
 		to:   to,
+		id:   id,
 	}, nil
 }
 
 func (b *tmpSyncBranch) cleanup() {
-	if b.ignoreCleanup {
+	if b.ignoreCleanup || !isBranchExist(b.name) {
 		return
 	}
 	_, err := exec.OutputErr("git", "branch", "-D", b.name)
@@ -1,6 +1,8 @@
 package dx
 
 import (
+	"context"
+	"os/exec"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -401,3 +403,183 @@ func TestSync_CodeMultipleConflict(t *testing.T) {
 	assert.Equal(t, actualCommits[0].subCommit[1].short, "feat(lib): update lib")
 	assertNormalTeardown(t, clientDir)
 }
+
+func TestSync_Interrupted(t *testing.T) {
+	_, clientDir := newGitTest(t)
+
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	twrite(t, clientDir+"/content", "hello world")
+	trun(t, clientDir, "git", "add", "content")
+	err := trunMainCommand(t, "commit", "-m", "commit message")
+	require.NoError(t, err)
+
+	t.Log("sync is interrupted before it starts, see TestSync_FailedDuringCherryPick for rollback")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cmd := NewMainCmd()
+	cmd.SetArgs([]string{"sync", "dev"})
+	err = cmd.ExecuteContext(ctx)
+	require.ErrorContains(t, err, "sync is interrupted")
+	assert.Equal(t, "feature", tgetHeadBranch(t, clientDir))
+	assertNormalTeardown(t, clientDir)
+	path, err := getSyncLockPath("dev")
+	require.NoError(t, err)
+	assert.NoFileExists(t, path)
+
+	err = trunMainCommand(t, "sync", "dev")
+	require.NoError(t, err)
+	assertNormalTeardown(t, clientDir)
+}
+
+func TestSync_FailedDuringCherryPick(t *testing.T) {
+	serverDir, clientDir := newGitTest(t)
+
+	t.Log("server - dev already has the change of the second commit")
+	trun(t, serverDir, "git", "checkout", "dev")
+	twrite(t, serverDir+"/b", "b\n")
+	trun(t, serverDir, "git", "add", "b")
+	trun(t, serverDir, "git", "commit", "-m", "feat: b without using dx")
+	trun(t, serverDir, "git", "checkout", "main")
+
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	twrite(t, clientDir+"/a", "a\n")
+	trun(t, clientDir, "git", "add", "a")
+	err := trunMainCommand(t, "commit", "-m", "feat: a")
+	require.NoError(t, err)
+	twrite(t, clientDir+"/b", "b\n")
+	trun(t, clientDir, "git", "add", "b")
+	err = trunMainCommand(t, "commit", "-m", "feat: b")
+	require.NoError(t, err)
+	trun(t, clientDir, "git", "fetch", "origin", "dev:dev")
+	devHead := trun(t, clientDir, "git", "rev-parse", "dev")
+	featureHead := trun(t, clientDir, "git", "rev-parse", "feature")
+
+	t.Log("cherry-pick of the second commit is failed after the first one is picked")
+	err = trunMainCommand(t, "--debug", "sync", "dev")
+	require.Error(t, err)
+	assert.Equal(t, "feature", tgetHeadBranch(t, clientDir))
+	assert.Equal(t, featureHead, trun(t, clientDir, "git", "rev-parse", "feature"))
+	assert.Equal(t, devHead, trun(t, clientDir, "git", "rev-parse", "dev"))
+	assert.NoFileExists(t, clientDir+"/.git/CHERRY_PICK_HEAD")
+	assert.Empty(t, trun(t, clientDir, "git", "status", "--porcelain"))
+	assertNormalTeardown(t, clientDir)
+	path, err := getSyncLockPath("dev")
+	require.NoError(t, err)
+	assert.NoFileExists(t, path)
+}
+
+func TestSync_DetectKilledSync(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	trun(t, clientDir, "git", "branch", "tmp-sync-killed")
+
+	t.Log("sync process is killed")
+	path, err := getSyncLockPath("dev")
+	require.NoError(t, err)
+	lock := newSyncLock(path, "dev", "feature", "tmp-sync-killed")
+	deadCmd := exec.Command("true")
+	require.NoError(t, deadCmd.Run())
+	lock.PID = deadCmd.Process.Pid
+	require.NoError(t, lock.create())
+
+	err = trunMainCommand(t, "version")
+	require.NoError(t, err)
+	assert.NoFileExists(t, path, "stale lock must be removed after it's reported")
+}
+
+func TestSync_ConfigTargetsAndVerify(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	trun(t, clientDir, "git", "config", "dx.sync.targets", "dev")
+
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	twrite(t, clientDir+"/content", "hello world")
+	trun(t, clientDir, "git", "add", "content")
+	err := trunMainCommand(t, "commit", "-m", "commit message")
+	require.NoError(t, err)
+
+	err = trunMainCommand(t, "sync", "beta")
+	require.ErrorContains(t, err, "beta is not in sync.targets (dev) from git config")
+
+	t.Log("verify command is failed")
+	trun(t, clientDir, "git", "config", "dx.sync.verify", "grep -q bye content")
+	devBefore := trevParse(t, clientDir, "dev")
+	err = trunMainCommand(t, "sync")
+	require.ErrorContains(t, err, `verify command "grep -q bye content" failed`)
+	assert.Equal(t, devBefore, trevParse(t, clientDir, "dev"))
+	assert.Equal(t, "feature", tgetHeadBranch(t, clientDir))
+	assertNormalTeardown(t, clientDir)
+
+	t.Log("verify command is passed")
+	trun(t, clientDir, "git", "config", "dx.sync.verify", "grep -q hello content")
+	err = trunMainCommand(t, "sync")
+	require.NoError(t, err)
+	actualCommits := tgetCommits(t, clientDir, "dev")
+	assert.Equal(t, "sync from feature", actualCommits[0].short)
+	assertNormalTeardown(t, clientDir)
+}
+
+func TestSync_CherryPickStrategy(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	trun(t, clientDir, "git", "config", "dx.sync.strategy", "cherry-pick")
+	trun(t, clientDir, "git", "config", "dx.trailer.key", "Change-Id")
+
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	twrite(t, clientDir+"/content", "hello world")
+	trun(t, clientDir, "git", "add", "content")
+	err := trunMainCommand(t, "commit", "-m", "commit message")
+	require.NoError(t, err)
+	twrite(t, clientDir+"/content", "update")
+	trun(t, clientDir, "git", "add", "content")
+	err = trunMainCommand(t, "commit", "-m", "fix: update")
+	require.NoError(t, err)
+
+	err = trunMainCommand(t, "sync", "dev")
+	require.NoError(t, err)
+	tgitLog(t, clientDir, "dev")
+	actualCommits := tgetCommits(t, clientDir, "dev")
+	assert.Equal(t, "fix: update", actualCommits[0].short)
+	assert.Equal(t, "commit message", actualCommits[1].short)
+	assert.Contains(t, actualCommits[0].message, "Change-Id: ")
+
+	t.Log("synced commits are detected by change id")
+	err = trunMainCommand(t, "sync", "dev")
+	require.NoError(t, err)
+	assert.Len(t, tgetCommits(t, clientDir, "dev"), len(actualCommits))
+	assertNormalTeardown(t, clientDir)
+}
+
+func TestSync_RenumberMigrations(t *testing.T) {
+	serverDir, clientDir := newGitTest(t)
+	trun(t, clientDir, "git", "config", "dx.resolvers.migrations", "db")
+
+	t.Log("server - dev branch has a new migration")
+	trun(t, serverDir, "git", "checkout", "dev")
+	trun(t, serverDir, "mkdir", "db")
+	twrite(t, serverDir+"/db/000001_init.up.sql", "create table a ();\n")
+	twrite(t, serverDir+"/db/000001_init.down.sql", "drop table a;\n")
+	trun(t, serverDir, "git", "add", "db")
+	trun(t, serverDir, "git", "commit", "-m", "add init migration")
+	trun(t, serverDir, "git", "checkout", "main")
+
+	t.Log("client - feature adds a migration with the same version")
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	trun(t, clientDir, "mkdir", "db")
+	twrite(t, clientDir+"/db/000001_users.up.sql", "create table users ();\n")
+	twrite(t, clientDir+"/db/000001_users.down.sql", "drop table users;\n")
+	trun(t, clientDir, "git", "add", "db")
+	err := trunMainCommand(t, "commit", "-m", "add users migration")
+	require.NoError(t, err)
+
+	err = trunMainCommand(t, "--debug", "sync", "dev")
+	require.NoError(t, err)
+	assert.Equal(t, "feature", tgetHeadBranch(t, clientDir))
+	files := trun(t, clientDir, "git", "ls-tree", "--name-only", "dev", "db/")
+	assert.Equal(t, `db/000001_init.down.sql
+db/000001_init.up.sql
+db/000002_users.down.sql
+db/000002_users.up.sql
+`, files)
+	files = trun(t, clientDir, "git", "ls-tree", "--name-only", "feature", "db/")
+	assert.Equal(t, "db/000001_users.down.sql\ndb/000001_users.up.sql\n", files)
+	assertNormalTeardown(t, clientDir)
+}
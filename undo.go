@@ -0,0 +1,129 @@
+package dx
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kitimark/dx/pkg/exec"
+	"github.com/spf13/cobra"
+)
+
+func NewUndoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:  "undo",
+		Long: "undo restores the refs changed by the last operation, run it again to redo",
+		Args: cobra.NoArgs,
+		RunE: journaled(cmdUndoRun),
+	}
+	return cmd
+}
+
+func cmdUndoRun(cmd *cobra.Command, _ []string) error {
+	ops, err := readOperations()
+	if err != nil {
+		return err
+	}
+	if len(ops) == 0 {
+		return errors.New("no operation to undo")
+	}
+	op := ops[len(ops)-1]
+	cmd.SilenceUsage = true
+	slog.Info("undo operation", "id", op.ID, "command", op.command())
+
+	err = checkOperationIsLatest(op)
+	if err != nil {
+		return err
+	}
+
+	cherryPicking, err := isCherryPickInProgress(".")
+	if err != nil {
+		return err
+	}
+	if cherryPicking {
+		out, err := exec.OutputErr("git", "cherry-pick", "--abort")
+		if err != nil {
+			return fmt.Errorf("cannot abort cherry-pick: %s: %w", out, err)
+		}
+	}
+
+	restored := map[string]bool{}
+	if op.HeadBefore != op.HeadAfter {
+		if strings.HasPrefix(op.HeadBefore, "refs/heads/") {
+			for _, r := range op.Refs {
+				if r.Name == op.HeadBefore {
+					err = restoreRef(r)
+					if err != nil {
+						return err
+					}
+					restored[r.Name] = true
+				}
+			}
+			_, err = exec.OutputErr("git", "checkout", shortRef(op.HeadBefore))
+		} else {
+			_, err = exec.OutputErr("git", "checkout", "--detach", op.HeadBefore)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot checkout %s: %w", shortRef(op.HeadBefore), err)
+		}
+	}
+
+	for _, r := range op.Refs {
+		if restored[r.Name] {
+			continue
+		}
+		if r.Name == op.HeadBefore {
+			out, err := exec.OutputErr("git", "reset", "--keep", r.Before)
+			if err != nil {
+				return fmt.Errorf("cannot reset %s: %s: %w", shortRef(r.Name), out, err)
+			}
+			continue
+		}
+		err = restoreRef(r)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkOperationIsLatest returns error if the refs are moved after the operation
+func checkOperationIsLatest(op *operation) error {
+	head, err := getHeadRef()
+	if err != nil {
+		return err
+	}
+	if head != op.HeadAfter {
+		return fmt.Errorf("HEAD is moved to %s after operation %s, cannot undo",
+			shortRef(head), op.ID)
+	}
+	refs, err := getBranchRefs()
+	if err != nil {
+		return err
+	}
+	for _, r := range op.Refs {
+		if refs[r.Name] != r.After {
+			return fmt.Errorf("%s is moved to %s after operation %s, cannot undo",
+				shortRef(r.Name), shortHash(refs[r.Name]), op.ID)
+		}
+	}
+	return nil
+}
+
+func restoreRef(r refChange) error {
+	var out string
+	var err error
+	switch {
+	case r.Before == "":
+		out, err = exec.OutputErr("git", "update-ref", "-d", r.Name, r.After)
+	case r.After == "":
+		out, err = exec.OutputErr("git", "update-ref", r.Name, r.Before)
+	default:
+		out, err = exec.OutputErr("git", "update-ref", r.Name, r.Before, r.After)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot restore %s: %s: %w", r.Name, out, err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package dx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndo_Commit(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	before := trevParse(t, clientDir, "feature")
+
+	twrite(t, clientDir+"/content", "hello world")
+	trun(t, clientDir, "git", "add", "content")
+	err := trunMainCommand(t, "commit", "-m", "commit message")
+	require.NoError(t, err)
+	after := trevParse(t, clientDir, "feature")
+
+	err = trunMainCommand(t, "undo")
+	require.NoError(t, err)
+	assert.Equal(t, before, trevParse(t, clientDir, "feature"))
+	assert.Equal(t, "feature", tgetHeadBranch(t, clientDir))
+
+	t.Log("undo the undo operation")
+	err = trunMainCommand(t, "undo")
+	require.NoError(t, err)
+	assert.Equal(t, after, trevParse(t, clientDir, "feature"))
+	assert.Equal(t, "hello world", tread(t, clientDir+"/content"))
+
+	ops, err := readOperations()
+	require.NoError(t, err)
+	require.Len(t, ops, 3)
+	assert.Equal(t, "dx commit", ops[0].Command)
+	assert.Equal(t, []string{"--message=commit message"}, ops[0].Args)
+	assert.Equal(t, "dx undo", ops[2].Command)
+	err = trunMainCommand(t, "op", "log")
+	assert.NoError(t, err)
+}
+
+func TestUndo_Sync(t *testing.T) {
+	serverDir, clientDir := newGitTest(t)
+
+	trun(t, serverDir, "git", "checkout", "dev")
+	twrite(t, serverDir+"/srv_feature1", "srv_feature1")
+	trun(t, serverDir, "git", "add", "srv_feature1")
+	trun(t, serverDir, "git", "commit", "-m", "feat: server feature 1")
+
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	twrite(t, clientDir+"/content", "hello world")
+	trun(t, clientDir, "git", "add", "content")
+	err := trunMainCommand(t, "commit", "-m", "commit message")
+	require.NoError(t, err)
+	devBefore := trevParse(t, clientDir, "dev")
+
+	err = trunMainCommand(t, "sync", "dev")
+	require.NoError(t, err)
+	assert.NotEqual(t, devBefore, trevParse(t, clientDir, "dev"))
+
+	err = trunMainCommand(t, "undo")
+	require.NoError(t, err)
+	assert.Equal(t, devBefore, trevParse(t, clientDir, "dev"))
+	assert.Equal(t, "feature", tgetHeadBranch(t, clientDir))
+}
+
+func TestUndo_SyncCodeConflict(t *testing.T) {
+	serverDir, clientDir := newGitTest(t)
+
+	trun(t, serverDir, "git", "checkout", "dev")
+	twrite(t, serverDir+"/main", "srv_feature1\n")
+	trun(t, serverDir, "git", "add", "main")
+	trun(t, serverDir, "git", "commit", "-m", "feat: server feature 1")
+
+	trun(t, clientDir, "git", "checkout", "-b", "client_feature1")
+	twrite(t, clientDir+"/main", "client_feature1\n")
+	trun(t, clientDir, "git", "add", "main")
+	err := trunMainCommand(t, "commit", "-m", "feat: client feature 1")
+	require.NoError(t, err)
+	devBefore := trevParse(t, clientDir, "dev")
+
+	err = trunMainCommand(t, "sync", "dev")
+	require.ErrorContains(t, err, "code conflict")
+	assertBranchExist(t, clientDir, "tmp-sync*")
+
+	err = trunMainCommand(t, "undo")
+	require.NoError(t, err)
+	assert.Equal(t, "client_feature1", tgetHeadBranch(t, clientDir))
+	assert.Equal(t, devBefore, trevParse(t, clientDir, "dev"))
+	assertNormalTeardown(t, clientDir)
+	assert.Equal(t, "client_feature1\n", tread(t, clientDir+"/main"))
+
+	t.Log("the paused sync lock is stale after undo")
+	err = trunMainCommand(t, "sync", "dev")
+	require.ErrorContains(t, err, "code conflict")
+}
+
+func TestUndo_RefMovedAfterOperation(t *testing.T) {
+	_, clientDir := newGitTest(t)
+	trun(t, clientDir, "git", "checkout", "-b", "feature")
+	twrite(t, clientDir+"/content", "hello world")
+	trun(t, clientDir, "git", "add", "content")
+	err := trunMainCommand(t, "commit", "-m", "commit message")
+	require.NoError(t, err)
+
+	trun(t, clientDir, "git", "commit", "--allow-empty", "-m", "commit without dx")
+	err = trunMainCommand(t, "undo")
+	require.ErrorContains(t, err, "feature is moved")
+}
@@ -3,10 +3,12 @@ package dx
 import (
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/kitimark/dx/pkg/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/mod/modfile"
@@ -29,6 +31,14 @@ func newGitTest(t *testing.T) (string, string) {
 	require.NoError(t, err)
 	err = os.Setenv("GIT_CONFIG_GLOBAL", tmpdir+"/git/config")
 	require.NoError(t, err)
+	t.Setenv("XDG_CONFIG_HOME", tmpdir+"/xdg")
+	// go command writes telemetry counters into the user config dir in background,
+	// turn it off so the tmpdir can be removed
+	require.NoError(t, os.MkdirAll(tmpdir+"/xdg/go/telemetry", 0700))
+	require.NoError(t, os.WriteFile(tmpdir+"/xdg/go/telemetry/mode", []byte("off"), 0600))
+	t.Cleanup(func() {
+		cfg = config.Default()
+	})
 	t.Cleanup(func() {
 		err = os.RemoveAll(tmpdir)
 		if err != nil {
@@ -187,6 +197,11 @@ func tgetHeadBranch(t *testing.T, dir string) string {
 	return strings.TrimSpace(out)
 }
 
+func trevParse(t *testing.T, dir, rev string) string {
+	t.Helper()
+	return strings.TrimSpace(trun(t, dir, "git", "rev-parse", rev))
+}
+
 func tgitLog(t *testing.T, dir string, branches ...string) {
 	t.Helper()
 	args := []string{"log", "--graph", "--decorate"}
@@ -258,3 +273,35 @@ func treadGoMod(t *testing.T, file string) *modfile.File {
 	require.NoError(t, err)
 	return gomod
 }
+
+// tmergeConflict commits base files on main, changes them on feature and main branches and
+// merges feature into main, the merge must stop with conflicts. files are relative to dir
+func tmergeConflict(t *testing.T, dir string, base, feature, main map[string]string) {
+	t.Helper()
+	write := func(files map[string]string) {
+		for name, content := range files {
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+			twrite(t, path, content)
+		}
+		trun(t, dir, "git", "add", ".")
+	}
+
+	t.Log("main - commit base files")
+	write(base)
+	trun(t, dir, "git", "commit", "-m", "init")
+
+	t.Log("feature - change files")
+	trun(t, dir, "git", "checkout", "-b", "feature")
+	write(feature)
+	trun(t, dir, "git", "commit", "-m", "feature")
+
+	t.Log("main - change files")
+	trun(t, dir, "git", "checkout", "main")
+	write(main)
+	trun(t, dir, "git", "commit", "-m", "main")
+
+	t.Log("main - merge feature with conflicts")
+	_, err := trunErr(t, dir, "git", "merge", "feature")
+	require.Error(t, err)
+}